@@ -0,0 +1,61 @@
+package gotreap
+
+// requireAggregate panics unless the treap was constructed with an aggregate function.
+func (t *Treap[T]) requireAggregate() {
+	if t.traits == nil || t.traits.aggregate == nil {
+		panic("treap has no aggregate configured, use Options.Aggregate")
+	}
+}
+
+// prefixLen returns the number of leading elements satisfying leftCond.
+func (t *Treap[T]) prefixLen(leftCond leftCondition[T]) int {
+	node, index := t.root.lookupRightmostMatch(leftCond, 0)
+	if node == nil {
+		return 0
+	}
+	return index + 1
+}
+
+// Aggregate returns the aggregate of all values in the treap in O(1).
+// The ok flag is false when the treap is empty.
+// Panics if the treap has no aggregate configured.
+func (t *Treap[T]) Aggregate() (result T, ok bool) {
+	t.requireAggregate()
+	if t.root == nil {
+		return result, false
+	}
+	return t.root.subtreeAggregate(), true
+}
+
+// AggregateIndexRange returns the aggregate of the values with indices in [from, to).
+// Indices are clamped to the treap bounds and ok is false when the range is empty.
+// Panics if the treap has no aggregate configured.
+func (t *Treap[T]) AggregateIndexRange(from int, to int) (result T, ok bool) {
+	t.requireAggregate()
+	return t.root.aggregateIndexRange(max(from, 0), min(to, t.root.safeSize()), 0)
+}
+
+// AggregateRange returns the aggregate of the values between startValue and endValue.
+// Each bound is included only when its inclusive flag is true, matching CountRange,
+// and ok is false when no value falls in the range.
+// Panics if the treap has no aggregate configured, if endValue < startValue,
+// or if startValue == endValue with non-inclusive bounds.
+func (t *Treap[T]) AggregateRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) (result T, ok bool) {
+	t.requireAggregate()
+	from, to := t.valueRangeIndices(startValue, inclusiveStart, endValue, inclusiveEnd)
+	return t.root.aggregateIndexRange(from, to, 0)
+}
+
+// FindFirstPrefix returns the first node whose prefix aggregate satisfies pred, together with its index.
+// The prefix aggregate of a node combines every value from the leftmost one up to and including it.
+// pred must be monotone: once true for some node it must stay true for every node to its right,
+// e.g. "running sum exceeds X" over non-negative weights. Returns nil if pred never holds.
+// Panics if the treap has no aggregate configured.
+func (t *Treap[T]) FindFirstPrefix(pred func(prefix T) bool) (node *Node[T], index int) {
+	t.requireAggregate()
+
+	var acc T
+	return t.root.lookupLeftmostUnmatchPrefix(func(prefix T, nodeValue T, nodeIndex int) bool {
+		return !pred(prefix)
+	}, acc, false, 0)
+}
@@ -0,0 +1,192 @@
+package gotreap
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sumInts(a, b int) int { return a + b }
+
+func TestAggregateSum(t *testing.T) {
+	tr := NewTreapWithOptions(cmp.Less[int], Options[int]{RandFn: staticRand(), Aggregate: sumInts}, 5, 1, 4, 2, 3)
+
+	total, ok := tr.Aggregate()
+	require.True(t, ok)
+	require.Equal(t, 15, total)
+
+	sum, ok := tr.AggregateIndexRange(1, 4)
+	require.True(t, ok)
+	require.Equal(t, 2+3+4, sum)
+
+	sum, ok = tr.AggregateRange(2, false, 5, false)
+	require.True(t, ok)
+	require.Equal(t, 3+4, sum)
+
+	_, ok = tr.AggregateRange(6, true, 9, true)
+	require.False(t, ok)
+	_, ok = tr.AggregateIndexRange(3, 3)
+	require.False(t, ok)
+
+	sum, ok = tr.AggregateIndexRange(-10, 10)
+	require.True(t, ok)
+	require.Equal(t, 15, sum)
+
+	tr.InsertRight(10)
+	tr.EraseAll(1)
+	total, _ = tr.Aggregate()
+	require.Equal(t, 24, total)
+
+	require.Panics(t, func() { tr.AggregateRange(3, true, 2, true) })
+}
+
+func TestAggregateNonCommutative(t *testing.T) {
+	concat := func(a, b string) string { return a + b }
+	tr := NewTreapWithOptions(cmp.Less[string], Options[string]{RandFn: staticRand(), Aggregate: concat}, "d", "a", "c", "b", "e")
+
+	all, _ := tr.Aggregate()
+	require.Equal(t, "abcde", all)
+
+	part, _ := tr.AggregateRange("b", true, "d", true)
+	require.Equal(t, "bcd", part)
+
+	left, right := tr.SplitAfter("b")
+	leftAll, _ := left.Aggregate()
+	rightAll, _ := right.Aggregate()
+	require.Equal(t, "ab", leftAll)
+	require.Equal(t, "cde", rightAll)
+
+	merged := Merge(left, right)
+	merged.InsertLeft("bb")
+	all, _ = merged.Aggregate()
+	require.Equal(t, "abbbcde", all)
+}
+
+func TestAggregateCustomStruct(t *testing.T) {
+	type stats struct {
+		Key   int
+		Count int
+		Min   int
+		Max   int
+	}
+	combine := func(a, b stats) stats {
+		return stats{Count: a.Count + b.Count, Min: min(a.Min, b.Min), Max: max(a.Max, b.Max)}
+	}
+	point := func(k int) stats { return stats{Key: k, Count: 1, Min: k, Max: k} }
+
+	tr := NewTreapWithOptions(func(a, b stats) bool { return a.Key < b.Key }, Options[stats]{Aggregate: combine},
+		point(7), point(3), point(9), point(1))
+
+	res, ok := tr.AggregateRange(point(2), true, point(8), true)
+	require.True(t, ok)
+	require.Equal(t, 2, res.Count)
+	require.Equal(t, 3, res.Min)
+	require.Equal(t, 7, res.Max)
+}
+
+func TestAggregateRequiresConfiguration(t *testing.T) {
+	tr := NewAutoOrderTreap(1, 2, 3)
+	require.Panics(t, func() { tr.Aggregate() })
+	require.Panics(t, func() { tr.AggregateIndexRange(0, 1) })
+	require.Panics(t, func() { tr.AggregateRange(1, true, 2, true) })
+}
+
+func TestAggregateMatchesBruteForce(t *testing.T) {
+	gcd := func(a, b int) int {
+		for b != 0 {
+			a, b = b, a%b
+		}
+		return a
+	}
+
+	for name, fn := range map[string]func(a, b int) int{"sum": sumInts, "min": func(a, b int) int { return min(a, b) }, "gcd": gcd} {
+		t.Run(name, func(t *testing.T) {
+			tr := NewTreapWithOptions(cmp.Less[int], Options[int]{RandFn: staticRand(), Aggregate: fn})
+			var arr []int
+
+			rnd := rand.New(rand.NewPCG(1, 2))
+			for range 1000 {
+				v := rnd.IntN(500) * 6
+				if rnd.IntN(4) == 0 {
+					tr.EraseLeftmost(v, 1)
+					if pos, found := slices.BinarySearch(arr, v); found {
+						arr = slices.Delete(arr, pos, pos+1)
+					}
+				} else {
+					tr.InsertRight(v)
+					pos, _ := slices.BinarySearch(arr, v+1)
+					arr = slices.Insert(arr, pos, v)
+				}
+
+				from := rnd.IntN(len(arr) + 1)
+				to := from + rnd.IntN(len(arr)-from+1)
+				res, ok := tr.AggregateIndexRange(from, to)
+				require.Equal(t, from < to, ok)
+				if ok {
+					expected := arr[from]
+					for _, x := range arr[from+1 : to] {
+						expected = fn(expected, x)
+					}
+					require.Equal(t, expected, res)
+				}
+			}
+		})
+	}
+}
+
+func TestFindFirstPrefixWeighted(t *testing.T) {
+	type item struct {
+		Key    int
+		Weight int
+	}
+	combine := func(a, b item) item { return item{Key: b.Key, Weight: a.Weight + b.Weight} }
+	tr := NewTreapWithOptions(func(a, b item) bool { return a.Key < b.Key }, Options[item]{RandFn: staticRand(), Aggregate: combine},
+		item{1, 5}, item{2, 0}, item{3, 7}, item{4, 1}, item{5, 2})
+
+	node, idx := tr.FindFirstPrefix(func(prefix item) bool { return prefix.Weight > 5 })
+	require.Equal(t, 2, idx)
+	require.Equal(t, 3, node.Value().Key)
+
+	node, idx = tr.FindFirstPrefix(func(prefix item) bool { return prefix.Weight >= 5 })
+	require.Equal(t, 0, idx)
+	require.Equal(t, 1, node.Value().Key)
+
+	node, _ = tr.FindFirstPrefix(func(prefix item) bool { return prefix.Weight > 15 })
+	require.Nil(t, node)
+
+	require.Panics(t, func() {
+		NewAutoOrderTreap(1).FindFirstPrefix(func(int) bool { return true })
+	})
+}
+
+func TestFindFirstPrefixMatchesLinearScan(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(4, 2))
+	values := make([]int, 300)
+	for i := range values {
+		values[i] = rnd.IntN(10)
+	}
+	tr := NewTreapWithOptions(cmp.Less[int], Options[int]{RandFn: staticRand(), Aggregate: sumInts}, slices.Clone(values)...)
+	slices.Sort(values)
+
+	for threshold := range 1500 {
+		expected, running := -1, 0
+		for i, v := range values {
+			running += v
+			if running > threshold {
+				expected = i
+				break
+			}
+		}
+
+		node, idx := tr.FindFirstPrefix(func(prefix int) bool { return prefix > threshold })
+		if expected < 0 {
+			require.Nil(t, node)
+			continue
+		}
+		require.Equal(t, expected, idx)
+		require.Equal(t, idx, node.Index())
+	}
+}
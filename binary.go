@@ -0,0 +1,163 @@
+package gotreap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// binaryMagic opens every encoded treap.
+var binaryMagic = [4]byte{'G', 'T', 'R', 'P'}
+
+// binaryVersion is the current version of the binary format.
+const binaryVersion = 1
+
+var (
+	// ErrInvalidHeader is returned when the input does not start with a valid treap header.
+	ErrInvalidHeader = errors.New("gotreap: invalid binary header")
+	// ErrChecksumMismatch is returned when the stored checksum does not match the decoded data.
+	ErrChecksumMismatch = errors.New("gotreap: checksum mismatch")
+	// ErrUnsortedData is returned when decoded values are not in ascending order.
+	ErrUnsortedData = errors.New("gotreap: decoded values are not sorted")
+)
+
+// Encoder writes treaps to an output stream in a versioned binary format:
+// a header with magic bytes, format version and element count, the elements
+// in sorted order as produced by the codec, and a CRC-32 checksum of everything before it.
+type Encoder[T any] struct {
+	w     *bufio.Writer
+	codec Codec[T]
+	buf   []byte
+}
+
+// NewEncoder returns an encoder writing to w using codec for individual values.
+func NewEncoder[T any](w io.Writer, codec Codec[T]) *Encoder[T] {
+	return &Encoder[T]{
+		w:     bufio.NewWriter(w),
+		codec: codec,
+	}
+}
+
+// Encode writes every value of t to the stream, one element at a time.
+func (e *Encoder[T]) Encode(t *Treap[T]) error {
+	crc := crc32.NewIEEE()
+	out := io.MultiWriter(e.w, crc)
+
+	e.buf = append(e.buf[:0], binaryMagic[:]...)
+	e.buf = append(e.buf, binaryVersion)
+	e.buf = binary.AppendUvarint(e.buf, uint64(t.Size()))
+	if _, err := out.Write(e.buf); err != nil {
+		return err
+	}
+
+	for value := range t.Values() {
+		e.buf = e.codec.Append(e.buf[:0], value)
+		if _, err := out.Write(e.buf); err != nil {
+			return err
+		}
+	}
+
+	if _, err := e.w.Write(binary.BigEndian.AppendUint32(e.buf[:0], crc.Sum32())); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+// checksumReader feeds every byte it reads into a running checksum.
+type checksumReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (c *checksumReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.crc.Write(p[:n])
+	return n, err
+}
+
+func (c *checksumReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+// Decoder reads treaps written by an Encoder. It buffers its input,
+// so several treaps encoded back to back can be read with successive calls.
+type Decoder[T any] struct {
+	r     *bufio.Reader
+	codec Codec[T]
+}
+
+// NewDecoder returns a decoder reading from r using codec for individual values.
+func NewDecoder[T any](r io.Reader, codec Codec[T]) *Decoder[T] {
+	return &Decoder[T]{
+		r:     bufio.NewReader(r),
+		codec: codec,
+	}
+}
+
+// Decode replaces the contents of t with the next treap in the stream.
+// The tree is built in O(n) straight from the sorted stream, and t keeps its ordering,
+// random source and options. Values out of order under t's lessFn are rejected, and
+// t is left unchanged on any error. Equal values are resolved by t's duplicate policy.
+func (d *Decoder[T]) Decode(t *Treap[T]) error {
+	if t.lessFn == nil {
+		return ErrNoOrdering
+	}
+
+	in := &checksumReader{r: d.r, crc: crc32.NewIEEE()}
+
+	var header [len(binaryMagic) + 1]byte
+	if _, err := io.ReadFull(in, header[:]); err != nil {
+		return err
+	}
+	if [4]byte(header[:4]) != binaryMagic {
+		return ErrInvalidHeader
+	}
+	if header[4] != binaryVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, header[4])
+	}
+
+	count, err := binary.ReadUvarint(in)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidHeader, err)
+	}
+
+	builder := t.newUniqueBuilder()
+	var prev T
+	for i := uint64(0); i < count; i++ {
+		value, err := d.codec.Read(in)
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		if i > 0 && t.lessFn(value, prev) {
+			return ErrUnsortedData
+		}
+		builder.append(value)
+		prev = value
+	}
+
+	var trailer [4]byte
+	if _, err := io.ReadFull(d.r, trailer[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	if binary.BigEndian.Uint32(trailer[:]) != in.crc.Sum32() {
+		return ErrChecksumMismatch
+	}
+
+	t.root = builder.finish()
+	t.debugCheck()
+	return nil
+}
@@ -0,0 +1,131 @@
+package gotreap
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodeTreap[T any](t *testing.T, tr *Treap[T], codec Codec[T]) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf, codec).Encode(tr))
+	return buf.Bytes()
+}
+
+func TestBinaryRoundTripBuiltinCodecs(t *testing.T) {
+	ints := NewAutoOrderTreapWithRand(staticRand(), -5, 300, 0, 7, 7, -1<<40)
+	decodedInts := NewAutoOrderTreapWithRand[int](staticRand())
+	require.NoError(t, NewDecoder(bytes.NewReader(encodeTreap(t, ints, IntCodec[int]{})), IntCodec[int]{}).Decode(decodedInts))
+	require.Equal(t, mustValues(ints), mustValues(decodedInts))
+	requireHeapAndParents(t, decodedInts)
+
+	uints := NewAutoOrderTreapWithRand[uint16](staticRand(), 1, 65535, 256)
+	decodedUints := NewAutoOrderTreapWithRand[uint16](staticRand())
+	require.NoError(t, NewDecoder(bytes.NewReader(encodeTreap(t, uints, UintCodec[uint16]{})), UintCodec[uint16]{}).Decode(decodedUints))
+	requireTreapValues(t, decodedUints, 1, 256, 65535)
+
+	floats := NewAutoOrderTreapWithRand(staticRand(), 3.5, -0.25, 1e300)
+	decodedFloats := NewAutoOrderTreapWithRand[float64](staticRand())
+	require.NoError(t, NewDecoder(bytes.NewReader(encodeTreap(t, floats, FloatCodec[float64]{})), FloatCodec[float64]{}).Decode(decodedFloats))
+	requireTreapValues(t, decodedFloats, -0.25, 3.5, 1e300)
+
+	strs := NewAutoOrderTreapWithRand(staticRand(), "pear", "", "apple", "яблоко")
+	decodedStrs := NewAutoOrderTreapWithRand[string](staticRand())
+	require.NoError(t, NewDecoder(bytes.NewReader(encodeTreap(t, strs, StringCodec[string]{})), StringCodec[string]{}).Decode(decodedStrs))
+	requireTreapValues(t, decodedStrs, "", "apple", "pear", "яблоко")
+}
+
+func TestBinaryDecodeKeepsOptionsAndSupportsStreams(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewEncoder(&buf, IntCodec[int]{})
+	require.NoError(t, enc.Encode(NewAutoOrderTreap(3, 1, 2)))
+	require.NoError(t, enc.Encode(NewAutoOrderTreap[int]()))
+	require.NoError(t, enc.Encode(NewAutoOrderTreap(10)))
+
+	dec := NewDecoder(&buf, IntCodec[int]{})
+	tr := NewTreapWithOptions(cmp.Less[int], Options[int]{RandFn: staticRand(), Aggregate: sumInts})
+
+	require.NoError(t, dec.Decode(tr))
+	requireTreapValues(t, tr, 1, 2, 3)
+	total, _ := tr.Aggregate()
+	require.Equal(t, 6, total)
+
+	require.NoError(t, dec.Decode(tr))
+	require.True(t, tr.Empty())
+
+	require.NoError(t, dec.Decode(tr))
+	requireTreapValues(t, tr, 10)
+	tr.InsertLeft(5)
+	total, _ = tr.Aggregate()
+	require.Equal(t, 15, total)
+
+	require.ErrorIs(t, dec.Decode(tr), io.EOF)
+}
+
+func TestBinaryDecodeRejectsMalformedInput(t *testing.T) {
+	data := encodeTreap(t, NewAutoOrderTreap(1, 2, 3), IntCodec[int]{})
+
+	decode := func(data []byte) (*Treap[int], error) {
+		tr := NewAutoOrderTreapWithRand(staticRand(), 42)
+		return tr, NewDecoder(bytes.NewReader(data), IntCodec[int]{}).Decode(tr)
+	}
+
+	corrupted := slices.Clone(data)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	tr, err := decode(corrupted)
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	requireTreapValues(t, tr, 42)
+
+	badMagic := slices.Clone(data)
+	badMagic[0] = 'X'
+	_, err = decode(badMagic)
+	require.ErrorIs(t, err, ErrInvalidHeader)
+
+	badVersion := slices.Clone(data)
+	badVersion[4] = 99
+	_, err = decode(badVersion)
+	require.ErrorIs(t, err, ErrInvalidHeader)
+
+	_, err = decode(data[:len(data)-2])
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	_, err = decode(data[:7])
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// A well-formed stream with values out of order must not produce a broken tree.
+	unsorted := append(slices.Clone(binaryMagic[:]), binaryVersion)
+	unsorted = binary.AppendUvarint(unsorted, 2)
+	unsorted = binary.AppendVarint(unsorted, 5)
+	unsorted = binary.AppendVarint(unsorted, 1)
+	tr, err = decode(unsorted)
+	require.ErrorIs(t, err, ErrUnsortedData)
+	requireTreapValues(t, tr, 42)
+
+	var zero Treap[int]
+	require.ErrorIs(t, NewDecoder(bytes.NewReader(data), IntCodec[int]{}).Decode(&zero), ErrNoOrdering)
+}
+
+func TestBinaryRoundTripLarge(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(9, 9))
+	values := make([]int, 20000)
+	for i := range values {
+		values[i] = rnd.IntN(1000)
+	}
+	tr := NewAutoOrderTreapWithRand(staticRand(), values...)
+
+	decoded := NewAutoOrderTreapWithRand[int](staticRand())
+	require.NoError(t, NewDecoder(bytes.NewReader(encodeTreap(t, tr, IntCodec[int]{})), IntCodec[int]{}).Decode(decoded))
+
+	require.Equal(t, mustValues(tr), mustValues(decoded))
+	requireHeapAndParents(t, decoded)
+
+	idx := decoded.InsertRight(500)
+	require.Equal(t, 500, decoded.At(idx).Value())
+}
@@ -0,0 +1,111 @@
+package gotreap
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// priority returns the heightPriority for a node holding value at the given rank within its run of
+// equal values. The rank only matters for canonical treaps, see rankedPriority.
+func (t *Treap[T]) priority(value T, rank int) int {
+	if t.priorityHash == nil {
+		return t.randFn()
+	}
+	return rankedPriority(t.priorityHash(value), rank)
+}
+
+// rankedPriority derives the priority of an element with hash h from its rank within its run of
+// equal values. Equal values hash alike, so without the rank a run of duplicates would share one
+// priority and degenerate into a chain; the first element of a run keeps the plain hash priority.
+func rankedPriority(h uint64, rank int) int {
+	return hashPriority(h ^ mix64(uint64(rank)))
+}
+
+// hashPriority turns a hash into a non-negative priority.
+func hashPriority(h uint64) int {
+	return int(mix64(h) >> 1)
+}
+
+// mix64 is the splitmix64 finalizer. It spreads weak hashes (e.g. small integers hashed
+// to themselves) over all 64 bits.
+func mix64(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
+
+// CodecHash returns a hash function for Options.PriorityHash that hashes the binary encoding
+// of a value produced by codec, mixed with seed. The result only depends on the encoding and
+// the seed, so it is stable across processes and platforms, which keeps canonical tree shapes
+// reproducible in benchmarks and snapshots.
+func CodecHash[T any](codec Codec[T], seed uint64) func(value T) uint64 {
+	return func(value T) uint64 {
+		data := binary.LittleEndian.AppendUint64(make([]byte, 0, 64), seed)
+		h := fnv.New64a()
+		h.Write(codec.Append(data, value))
+		return h.Sum64()
+	}
+}
+
+// rerank re-derives the priorities of the run of elements equal to value in the tree rooted at root
+// after an edit shifted their ranks, and returns the new root. The run keeps its nodes.
+// Costs O(k + log n) for a run of k elements.
+func (t *Treap[T]) rerank(root *Node[T], value T) *Node[T] {
+	less, rest := root.split(t.condLess(value), 0)
+	run, greater := rest.split(t.condLeq(value), 0)
+	return merge(merge(less, t.rebuildRun(run)), greater)
+}
+
+// rebuildRun assigns every node of run, a tree of equal values, the priority of its rank
+// and returns the root of the rebuilt tree.
+func (t *Treap[T]) rebuildRun(run *Node[T]) *Node[T] {
+	nodes := make([]*Node[T], 0, run.safeSize())
+	for cur := run.Leftmost(); cur != nil; cur = cur.Next() {
+		nodes = append(nodes, cur)
+	}
+
+	var builder sortedBuilder[T]
+	for rank, node := range nodes {
+		node.left, node.right, node.parent = nil, nil, nil
+		node.heightPriority = t.priority(node.value, rank)
+		builder.append(node)
+	}
+	return builder.finish()
+}
+
+// joinRuns merges left and right. In a canonical treap a run of equal values spanning the seam
+// continues the ranks of left into right, so its priorities are re-derived.
+func (t *Treap[T]) joinRuns(left *Node[T], right *Node[T]) *Node[T] {
+	if !t.Canonical() || left == nil || right == nil {
+		return merge(left, right)
+	}
+	last := left.Rightmost().value
+	root := merge(left, right)
+	if !t.lessFn(last, right.Leftmost().value) {
+		root = t.rerank(root, last)
+	}
+	return root
+}
+
+// joinAfterErase merges left and right, the remainders around the erased tree. In a canonical
+// treap a run of equal values continuing from erased into right moves down in rank,
+// so its priorities are re-derived.
+func (t *Treap[T]) joinAfterErase(left *Node[T], erased *Node[T], right *Node[T]) *Node[T] {
+	root := merge(left, right)
+	if !t.Canonical() || erased == nil || right == nil {
+		return root
+	}
+	if last := erased.Rightmost().value; !t.lessFn(last, right.Leftmost().value) {
+		root = t.rerank(root, last)
+	}
+	return root
+}
+
+// Canonical reports whether the treap derives priorities from Options.PriorityHash,
+// making its shape a function of its contents.
+func (t *Treap[T]) Canonical() bool {
+	return t.priorityHash != nil
+}
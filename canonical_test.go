@@ -0,0 +1,151 @@
+package gotreap
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// shapeOf renders the structure of a tree, including priorities, as a string.
+func shapeOf[T any](node *Node[T]) string {
+	if node == nil {
+		return "."
+	}
+	return fmt.Sprintf("(%s %v/%d %s)", shapeOf(node.left), node.value, node.heightPriority, shapeOf(node.right))
+}
+
+func newCanonical(values ...int) *Treap[int] {
+	return NewTreapCmpWithOptions(cmp.Compare[int], Options[int]{PriorityHash: CodecHash[int](IntCodec[int]{}, 42)}, values...)
+}
+
+func TestCanonicalShapeIndependentOfHistory(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(1, 2))
+	values := make([]int, 500)
+	for i := range values {
+		values[i] = rnd.IntN(200)
+	}
+
+	bulk := newCanonical(values...)
+	require.True(t, bulk.Canonical())
+	require.NoError(t, bulk.Validate())
+
+	incremental := newCanonical()
+	for _, i := range rnd.Perm(len(values)) {
+		if i%2 == 0 {
+			incremental.InsertLeft(values[i])
+		} else {
+			incremental.InsertRight(values[i])
+		}
+	}
+	require.Equal(t, shapeOf(bulk.root), shapeOf(incremental.root))
+
+	// Inserting extra values and erasing them again restores the exact shape.
+	for v := 1000; v < 1100; v++ {
+		incremental.InsertRight(v)
+		incremental.InsertLeft(v % 200)
+	}
+	incremental.EraseRange(1000, true, 1100, false)
+	for v := 0; v < 100; v++ {
+		incremental.EraseLeftmost(v, 1)
+	}
+	require.NoError(t, incremental.Validate())
+	require.Equal(t, shapeOf(bulk.root), shapeOf(incremental.root))
+}
+
+func TestCanonicalShapeSurvivesSplitsAndSetOps(t *testing.T) {
+	whole := newCanonical(3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5)
+
+	left, right := newCanonical(3, 1, 4, 1, 5, 9, 2, 6, 5, 3, 5).SplitBefore(4)
+	require.Equal(t, shapeOf(whole.root), shapeOf(Merge(left, right).root))
+
+	union := Union(newCanonical(1, 3, 5, 5, 9), newCanonical(1, 2, 3, 4, 5, 6), MultiplicitySum)
+	require.Equal(t, shapeOf(whole.root), shapeOf(union.root))
+
+	node := whole.At(4)
+	whole.ReplaceValue(node, 7)
+	require.Equal(t, shapeOf(newCanonical(1, 1, 2, 3, 4, 5, 5, 5, 6, 7, 9).root), shapeOf(whole.root))
+	require.NoError(t, whole.Validate())
+}
+
+// heightOf returns the number of nodes on the longest root to leaf path.
+func heightOf[T any](node *Node[T]) int {
+	if node == nil {
+		return 0
+	}
+	return 1 + max(heightOf(node.left), heightOf(node.right))
+}
+
+func TestCanonicalRunsOfDuplicatesStayBalanced(t *testing.T) {
+	const n = 1 << 14
+	tr := newCanonical()
+	for i := range n {
+		if i%2 == 0 {
+			tr.InsertRight(7)
+		} else {
+			require.Equal(t, i, tr.InsertLeft(7))
+		}
+	}
+	require.NoError(t, tr.Validate())
+	require.Less(t, heightOf(tr.root), 64)
+
+	bulk := newCanonical(slices.Repeat([]int{7}, n)...)
+	require.Equal(t, shapeOf(bulk.root), shapeOf(tr.root))
+}
+
+func TestCanonicalShapeSurvivesEditsInsideRuns(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(3, 4))
+	tr := newCanonical()
+	for range 300 {
+		tr.InsertRight(rnd.IntN(10))
+	}
+
+	for range 300 {
+		switch rnd.IntN(8) {
+		case 0:
+			tr.EraseLeftmost(rnd.IntN(10), 1+rnd.IntN(3))
+		case 1:
+			tr.EraseAt(rnd.IntN(tr.Size()), 1+rnd.IntN(5))
+		case 2:
+			tr.EraseNode(tr.At(rnd.IntN(tr.Size())))
+		case 3:
+			tr.PopLeftmost()
+		case 4:
+			tr = Merge(tr.Cut(rnd.IntN(tr.Size())))
+		case 5:
+			node := tr.At(rnd.IntN(tr.Size()))
+			tr.InsertAfterNode(node, node.Value())
+		case 6:
+			tr.ReplaceValue(tr.At(rnd.IntN(tr.Size())), rnd.IntN(10))
+		default:
+			tr = Union(tr, newCanonical(rnd.IntN(10), rnd.IntN(10)), MultiplicitySum)
+		}
+		require.NoError(t, tr.Validate())
+		require.Equal(t, shapeOf(newCanonical(mustValues(tr)...).root), shapeOf(tr.root))
+		if tr.Size() < 50 {
+			tr.InsertRight(rnd.IntN(10))
+		}
+	}
+}
+
+func TestCanonicalValidateDetectsForeignPriorities(t *testing.T) {
+	tr := newCanonical(1, 2, 3)
+	tr.root.heightPriority++
+	err := tr.Validate()
+	require.ErrorIs(t, err, ErrInvariantViolated)
+	require.ErrorContains(t, err, "does not match the priority hash")
+}
+
+func TestCodecHash(t *testing.T) {
+	hash := CodecHash[string](StringCodec[string]{}, 7)
+	require.Equal(t, hash("treap"), hash("treap"))
+	require.NotEqual(t, hash("treap"), hash("heap"))
+	require.NotEqual(t, hash("treap"), CodecHash[string](StringCodec[string]{}, 8)("treap"))
+
+	// Stable across processes: pinned value for the FNV-1a based encoding.
+	require.Equal(t, uint64(0xe604843a24902d25), CodecHash[int](IntCodec[int]{}, 0)(1))
+	require.GreaterOrEqual(t, hashPriority(^uint64(0)), 0)
+}
@@ -0,0 +1,136 @@
+package gotreap
+
+import "iter"
+
+// ChangeKind classifies how a value differs between two versions of a treap.
+type ChangeKind int
+
+const (
+	// ChangeAdded marks a value present only in the newer version.
+	ChangeAdded ChangeKind = iota
+	// ChangeRemoved marks a value present only in the older version.
+	ChangeRemoved
+	// ChangeMultiplicity marks a value present in both versions a different number of times.
+	ChangeMultiplicity
+)
+
+// Change describes one value whose number of occurrences differs between two treaps.
+type Change[T any] struct {
+	Kind ChangeKind
+	// Value is taken from the newer version, or from the older one for removals.
+	Value T
+	// Before and After are the numbers of occurrences in the older and the newer version.
+	Before int
+	After  int
+}
+
+// newChange builds the change for a value occurring before times in the older version and after times in the newer one.
+func newChange[T any](value T, before, after int) Change[T] {
+	kind := ChangeMultiplicity
+	switch {
+	case before == 0:
+		kind = ChangeAdded
+	case after == 0:
+		kind = ChangeRemoved
+	}
+	return Change[T]{Kind: kind, Value: value, Before: before, After: after}
+}
+
+// hasContentHash reports whether the treap caches content hashes.
+func (t *Treap[T]) hasContentHash() bool {
+	return t.traits != nil && t.traits.hash != nil
+}
+
+// Changes returns an iterator over the changes turning a into b, in ascending order of value.
+// Elements that are equal under the order are counted together, as InsertLeft and InsertRight
+// group duplicates, and a value is reported once when its count differs. Treap.Diff, in
+// contrast, compares exact contents and yields every unmatched element.
+// The walk is a linear merge of both treaps; when both use the same Options.ContentHash,
+// identical stretches are skipped by comparing hashes, so the cost grows with the number
+// of differences instead. Both treaps must use the same order and must not be modified
+// during iteration.
+func Changes[T any](a *Treap[T], b *Treap[T]) iter.Seq[Change[T]] {
+	if a.hasContentHash() && b.hasContentHash() {
+		return changesHashed(a, b)
+	}
+	return changesMerged(a, b)
+}
+
+// changesMerged walks both treaps in order, comparing the lengths of runs of equal values.
+func changesMerged[T any](a *Treap[T], b *Treap[T]) iter.Seq[Change[T]] {
+	return func(yield func(Change[T]) bool) {
+		nextA, stopA := iter.Pull(a.Values())
+		defer stopA()
+		nextB, stopB := iter.Pull(b.Values())
+		defer stopB()
+
+		va, okA := nextA()
+		vb, okB := nextB()
+		for okA || okB {
+			var value T
+			switch {
+			case !okB || (okA && a.lessFn(va, vb)):
+				value = va
+			default:
+				value = vb
+			}
+
+			before, after := 0, 0
+			for okA && !a.lessFn(value, va) {
+				before++
+				va, okA = nextA()
+			}
+			for okB && !a.lessFn(value, vb) {
+				after++
+				vb, okB = nextB()
+			}
+
+			if before != after && !yield(newChange(value, before, after)) {
+				return
+			}
+		}
+	}
+}
+
+// changesHashed skips identical stretches of a and b by content hash and compares
+// the runs of equal values at every position where they diverge.
+func changesHashed[T any](a *Treap[T], b *Treap[T]) iter.Seq[Change[T]] {
+	return func(yield func(Change[T]) bool) {
+		i, j := 0, 0
+		n, m := a.Size(), b.Size()
+		for i < n || j < m {
+			if i < n && j < m {
+				run := a.commonRun(i, b, j, min(n-i, m-j))
+				i, j = i+run, j+run
+				if i == n && j == m {
+					return
+				}
+			}
+
+			var value T
+			switch {
+			case j == m:
+				value = a.At(i).value
+			case i == n:
+				value = b.At(j).value
+			default:
+				value = a.At(i).value
+				if vb := b.At(j).value; !a.lessFn(value, vb) {
+					value = vb
+				}
+			}
+
+			// The run may have started inside the skipped stretch, so count it as a whole.
+			fromA, toA := a.equalRange(value)
+			fromB, toB := b.equalRange(value)
+			i, j = max(i, toA), max(j, toB)
+			if toB > fromB {
+				value = b.At(fromB).value
+			}
+
+			if toA-fromA != toB-fromB && !yield(newChange(value, toA-fromA, toB-fromB)) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package gotreap
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanges(t *testing.T) {
+	a := NewAutoOrderTreap(1, 2, 2, 3, 5, 5, 5, 8)
+	b := NewAutoOrderTreap(2, 3, 4, 5, 8, 8, 9)
+	expected := []Change[int]{
+		{Kind: ChangeRemoved, Value: 1, Before: 1, After: 0},
+		{Kind: ChangeMultiplicity, Value: 2, Before: 2, After: 1},
+		{Kind: ChangeAdded, Value: 4, Before: 0, After: 1},
+		{Kind: ChangeMultiplicity, Value: 5, Before: 3, After: 1},
+		{Kind: ChangeMultiplicity, Value: 8, Before: 1, After: 2},
+		{Kind: ChangeAdded, Value: 9, Before: 0, After: 1},
+	}
+	require.Equal(t, expected, slices.Collect(Changes(a, b)))
+
+	hashedA := newHashed(mustValues(a)...)
+	hashedB := newHashed(mustValues(b)...)
+	require.Equal(t, expected, slices.Collect(Changes(hashedA, hashedB)))
+
+	require.Empty(t, slices.Collect(Changes(a, a)))
+	require.Empty(t, slices.Collect(Changes(NewAutoOrderTreap[int](), NewAutoOrderTreap[int]())))
+	require.Equal(t, []Change[int]{{Kind: ChangeAdded, Value: 7, After: 2}},
+		slices.Collect(Changes(newHashed(), newHashed(7, 7))))
+
+	// Early termination.
+	for range Changes(a, b) {
+		break
+	}
+	for range Changes(hashedA, hashedB) {
+		break
+	}
+}
+
+func TestChangesTakesValuesFromNewerVersion(t *testing.T) {
+	type entry struct {
+		key   int
+		label string
+	}
+	byKey := func(a, b entry) int { return cmp.Compare(a.key, b.key) }
+	a := NewTreapCmp(byKey, entry{1, "old"}, entry{2, "old"})
+	b := NewTreapCmp(byKey, entry{1, "new"}, entry{1, "new"})
+
+	require.Equal(t, []Change[entry]{
+		{Kind: ChangeMultiplicity, Value: entry{1, "new"}, Before: 1, After: 2},
+		{Kind: ChangeRemoved, Value: entry{2, "old"}, Before: 1, After: 0},
+	}, slices.Collect(Changes(a, b)))
+
+	// The run of 1 starts inside the stretch skipped by hash, yet the value still comes from b.
+	hash := func(e entry) uint64 { return uint64(e.key)<<8 ^ uint64(len(e.label)) }
+	opts := Options[entry]{ContentHash: hash}
+	older := []entry{{1, "old"}, {1, "older"}}
+	newer := []entry{{1, "old"}}
+	expected := []Change[entry]{{Kind: ChangeMultiplicity, Value: entry{1, "old"}, Before: 2, After: 1}}
+	require.Equal(t, expected, slices.Collect(Changes(NewTreapCmp(byKey, older...), NewTreapCmp(byKey, newer...))))
+	require.Equal(t, expected, slices.Collect(Changes(NewTreapCmpWithOptions(byKey, opts, older...), NewTreapCmpWithOptions(byKey, opts, newer...))))
+}
+
+func TestChangesMatchesCounts(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(11, 12))
+	for range 50 {
+		var left, right []int
+		for range 300 {
+			v := rnd.IntN(100)
+			left = append(left, v)
+			if rnd.IntN(10) > 0 {
+				right = append(right, v)
+			}
+		}
+		for range 10 {
+			right = append(right, rnd.IntN(120))
+		}
+
+		before, after := map[int]int{}, map[int]int{}
+		for _, v := range left {
+			before[v]++
+		}
+		for _, v := range right {
+			after[v]++
+		}
+		var expected []Change[int]
+		for v := range 120 {
+			if before[v] != after[v] {
+				expected = append(expected, newChange(v, before[v], after[v]))
+			}
+		}
+
+		require.Equal(t, expected, slices.Collect(Changes(NewAutoOrderTreap(left...), NewAutoOrderTreap(right...))))
+		require.Equal(t, expected, slices.Collect(Changes(newHashed(left...), newHashed(right...))))
+	}
+}
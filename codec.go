@@ -0,0 +1,97 @@
+package gotreap
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// CodecReader is the input a Codec reads encoded values from.
+type CodecReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// Codec converts single values to and from their binary representation.
+type Codec[T any] interface {
+	// Append appends the encoding of value to dst and returns the extended slice.
+	Append(dst []byte, value T) []byte
+	// Read decodes one value from r.
+	Read(r CodecReader) (T, error)
+}
+
+// Signed is the set of signed integer types.
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is the set of unsigned integer types.
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// IntCodec encodes signed integers as zig-zag varints.
+type IntCodec[T Signed] struct{}
+
+func (IntCodec[T]) Append(dst []byte, value T) []byte {
+	return binary.AppendVarint(dst, int64(value))
+}
+
+func (IntCodec[T]) Read(r CodecReader) (T, error) {
+	v, err := binary.ReadVarint(r)
+	return T(v), err
+}
+
+// UintCodec encodes unsigned integers as varints.
+type UintCodec[T Unsigned] struct{}
+
+func (UintCodec[T]) Append(dst []byte, value T) []byte {
+	return binary.AppendUvarint(dst, uint64(value))
+}
+
+func (UintCodec[T]) Read(r CodecReader) (T, error) {
+	v, err := binary.ReadUvarint(r)
+	return T(v), err
+}
+
+// FloatCodec encodes floating point numbers as their 8-byte IEEE 754 representation.
+type FloatCodec[T ~float32 | ~float64] struct{}
+
+func (FloatCodec[T]) Append(dst []byte, value T) []byte {
+	return binary.BigEndian.AppendUint64(dst, math.Float64bits(float64(value)))
+}
+
+func (FloatCodec[T]) Read(r CodecReader) (T, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return T(math.Float64frombits(binary.BigEndian.Uint64(buf[:]))), nil
+}
+
+// StringCodec encodes strings as a varint length followed by their bytes.
+type StringCodec[T ~string] struct{}
+
+func (StringCodec[T]) Append(dst []byte, value T) []byte {
+	dst = binary.AppendUvarint(dst, uint64(len(value)))
+	return append(dst, value...)
+}
+
+func (StringCodec[T]) Read(r CodecReader) (T, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	// Read in chunks so a corrupted length cannot force a huge allocation up front.
+	var buf []byte
+	for remaining := n; remaining > 0; {
+		chunk := min(remaining, 64<<10)
+		start := len(buf)
+		buf = append(buf, make([]byte, chunk)...)
+		if _, err := io.ReadFull(r, buf[start:]); err != nil {
+			return "", io.ErrUnexpectedEOF
+		}
+		remaining -= chunk
+	}
+	return T(buf), nil
+}
@@ -0,0 +1,6 @@
+//go:build !gotreap_debug
+
+package gotreap
+
+// debugMode is disabled by default, see debug_on.go.
+const debugMode = false
@@ -0,0 +1,7 @@
+//go:build gotreap_debug
+
+package gotreap
+
+// debugMode makes every mutating treap operation validate the tree afterwards and panic on corruption.
+// Enabled with `go build -tags gotreap_debug`.
+const debugMode = true
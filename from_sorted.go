@@ -0,0 +1,36 @@
+package gotreap
+
+import (
+	"iter"
+	"slices"
+)
+
+// FromSorted builds a treap from seq in O(n), without sorting or comparing values.
+// seq must yield values in ascending order under lessFn. When verify is true every
+// value is checked against its predecessor and ErrUnsortedData is returned on the first
+// violation; otherwise unsorted input silently produces a treap with broken ordering.
+// Unless opts allow duplicates, runs of equal values are collapsed to the first one
+// (DuplicatesReject) or the last one (DuplicatesReplace).
+func FromSorted[T any](lessFn func(a T, b T) bool, seq iter.Seq[T], opts Options[T], verify bool) (*Treap[T], error) {
+	t := NewTreapWithOptions(lessFn, opts)
+
+	builder := t.newUniqueBuilder()
+	var prev T
+	first := true
+	for value := range seq {
+		if verify && !first && lessFn(value, prev) {
+			return nil, ErrUnsortedData
+		}
+		builder.append(value)
+		prev, first = value, false
+	}
+
+	t.root = builder.finish()
+	t.debugCheck()
+	return t, nil
+}
+
+// FromSortedSlice builds a treap from values in O(n), see FromSorted. The slice is not retained.
+func FromSortedSlice[T any](lessFn func(a T, b T) bool, values []T, opts Options[T], verify bool) (*Treap[T], error) {
+	return FromSorted(lessFn, slices.Values(values), opts, verify)
+}
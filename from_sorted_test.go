@@ -0,0 +1,69 @@
+package gotreap
+
+import (
+	"cmp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromSortedSlice(t *testing.T) {
+	values := []int{1, 2, 2, 5, 8, 8, 8, 13}
+	tr, err := FromSortedSlice(cmp.Less[int], values, Options[int]{RandFn: staticRand()}, true)
+	require.NoError(t, err)
+	requireTreapValues(t, tr, values...)
+	requireHeapAndParents(t, tr)
+
+	require.Equal(t, 3, tr.Count(8))
+	require.Equal(t, 1, tr.InsertLeft(2))
+	require.Equal(t, 5, tr.At(4).Value())
+
+	empty, err := FromSortedSlice(cmp.Less[int], nil, Options[int]{}, true)
+	require.NoError(t, err)
+	require.True(t, empty.Empty())
+}
+
+func TestFromSortedSequenceKeepsOptions(t *testing.T) {
+	seq := func(yield func(int) bool) {
+		for i := range 1000 {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	tr, err := FromSorted(cmp.Less[int], seq, Options[int]{RandFn: staticRand(), Aggregate: sumInts}, false)
+	require.NoError(t, err)
+	require.Equal(t, 1000, tr.Size())
+	requireHeapAndParents(t, tr)
+
+	total, ok := tr.Aggregate()
+	require.True(t, ok)
+	require.Equal(t, 999*1000/2, total)
+
+	sum, _ := tr.AggregateIndexRange(10, 20)
+	require.Equal(t, 145, sum)
+}
+
+func TestFromSortedVerification(t *testing.T) {
+	unsorted := []int{1, 3, 2}
+
+	tr, err := FromSortedSlice(cmp.Less[int], unsorted, Options[int]{}, true)
+	require.ErrorIs(t, err, ErrUnsortedData)
+	require.Nil(t, tr)
+
+	// Without verification the caller is trusted and values are kept in the given order,
+	// unless debug mode catches the broken tree.
+	if debugMode {
+		require.Panics(t, func() { FromSortedSlice(cmp.Less[int], unsorted, Options[int]{}, false) })
+	} else {
+		tr, err = FromSortedSlice(cmp.Less[int], unsorted, Options[int]{}, false)
+		require.NoError(t, err)
+		require.Equal(t, unsorted, slices.Collect(tr.Values()))
+	}
+
+	desc, err := FromSortedSlice(func(a, b int) bool { return a > b }, []int{9, 4, 4, 1}, Options[int]{}, true)
+	require.NoError(t, err)
+	requireTreapValues(t, desc, 9, 4, 4, 1)
+}
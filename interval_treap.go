@@ -0,0 +1,358 @@
+package gotreap
+
+import (
+	"cmp"
+	"iter"
+	"math/rand/v2"
+)
+
+// IntervalTreap stores closed intervals of type T with endpoints of type P, ordered by start and then end.
+// Every interval is also filed in a bucket at the topmost node whose start it contains. That node lies
+// on the search path of every point of the interval, so stabbing and overlap queries walk a single
+// path and report k intervals in O(log n + k). Every subtree additionally caches the interval with
+// the greatest end, which lets AnyOverlap find the first overlap in start order in O(log n).
+type IntervalTreap[T any, P any] struct {
+	startFn func(interval T) P
+	endFn   func(interval T) P
+	lessFn  func(a P, b P) bool
+	randFn  func() int
+	treap   *Treap[T]
+	// buckets holds the intervals filed at each node, entries where each interval node is filed.
+	buckets map[*Node[T]]*intervalBucket[T]
+	entries map[*Node[T]]bucketEntry[T]
+}
+
+// intervalBucket keeps the intervals filed at one node, all of which contain the node's start,
+// once ordered by start and once by descending end.
+type intervalBucket[T any] struct {
+	byStart *Treap[*Node[T]]
+	byEnd   *Treap[*Node[T]]
+}
+
+// bucketEntry locates an interval node within the bucket it is filed in.
+type bucketEntry[T any] struct {
+	home    *Node[T]
+	byStart *Node[*Node[T]]
+	byEnd   *Node[*Node[T]]
+}
+
+// NewAutoOrderIntervalTreap builds an interval treap using the natural ordering for endpoints of type P.
+// startFn and endFn extract the endpoints of an interval, with start not greater than end.
+func NewAutoOrderIntervalTreap[T any, P cmp.Ordered](startFn func(interval T) P, endFn func(interval T) P, values ...T) *IntervalTreap[T, P] {
+	return NewIntervalTreap(startFn, endFn, cmp.Less[P], values...)
+}
+
+// NewAutoOrderIntervalTreapWithRand builds an interval treap using the natural ordering for endpoints of type P and a custom random function.
+func NewAutoOrderIntervalTreapWithRand[T any, P cmp.Ordered](startFn func(interval T) P, endFn func(interval T) P, randFn func() int, values ...T) *IntervalTreap[T, P] {
+	return NewIntervalTreapWithRand(startFn, endFn, cmp.Less[P], randFn, values...)
+}
+
+// NewIntervalTreap constructs an interval treap using lessFn for endpoint ordering and optionally inserts values.
+func NewIntervalTreap[T any, P any](startFn func(interval T) P, endFn func(interval T) P, lessFn func(a P, b P) bool, values ...T) *IntervalTreap[T, P] {
+	return NewIntervalTreapWithRand(startFn, endFn, lessFn, rand.Int, values...)
+}
+
+// NewIntervalTreapWithRand constructs an interval treap using lessFn for endpoint ordering,
+// randFn for tree balancing, and optionally inserts values.
+func NewIntervalTreapWithRand[T any, P any](startFn func(interval T) P, endFn func(interval T) P, lessFn func(a P, b P) bool, randFn func() int, values ...T) *IntervalTreap[T, P] {
+	if startFn == nil || endFn == nil {
+		panic("startFn and endFn must not be nil")
+	}
+	if lessFn == nil {
+		panic("lessFn must not be nil")
+	}
+	if randFn == nil {
+		panic("randFn must not be nil")
+	}
+
+	intervalLess := func(a T, b T) bool {
+		startA, startB := startFn(a), startFn(b)
+		if lessFn(startA, startB) {
+			return true
+		}
+		if lessFn(startB, startA) {
+			return false
+		}
+		return lessFn(endFn(a), endFn(b))
+	}
+	latestEnd := func(a T, b T) T {
+		if lessFn(endFn(a), endFn(b)) {
+			return b
+		}
+		return a
+	}
+
+	s := &IntervalTreap[T, P]{
+		startFn: startFn,
+		endFn:   endFn,
+		lessFn:  lessFn,
+		randFn:  randFn,
+		treap:   NewTreapWithOptions(intervalLess, Options[T]{RandFn: randFn, Aggregate: latestEnd}, values...),
+		buckets: map[*Node[T]]*intervalBucket[T]{},
+		entries: map[*Node[T]]bucketEntry[T]{},
+	}
+	for node := range s.treap.Elements() {
+		s.file(node)
+	}
+	return s
+}
+
+// Insert adds interval, keeping duplicates, and returns its index in start order.
+// Panics if the interval ends before it starts.
+func (s *IntervalTreap[T, P]) Insert(interval T) (index int) {
+	if s.lessFn(s.endFn(interval), s.startFn(interval)) {
+		panic("interval end must not be lower than its start")
+	}
+	defer s.treap.debugCheck()
+
+	node := s.link(interval)
+	// Only the subtree node took over changed shape, so only the intervals filed there can move.
+	refile := s.unfileSubtree(node)
+	s.file(node)
+	for _, member := range refile {
+		s.file(member)
+	}
+	return node.Index()
+}
+
+// Delete removes one interval with the same start and end as interval and reports whether one was found.
+func (s *IntervalTreap[T, P]) Delete(interval T) bool {
+	node, _ := s.treap.FindLowerBound(interval)
+	if node == nil || s.treap.lessFn(interval, node.value) {
+		return false
+	}
+
+	refile := s.unfileSubtree(node)
+	if _, ok := s.entries[node]; ok {
+		s.unfile(node)
+	}
+	s.treap.EraseNode(node)
+	for _, member := range refile {
+		if member != node {
+			s.file(member)
+		}
+	}
+	return true
+}
+
+// Overlapping iterates over every interval sharing at least one point with [start, end] in O(log n + k)
+// for k reported intervals. Intervals containing start come first, in no particular order, followed by
+// the rest in start order. The treap must not be modified during iteration.
+// Panics if end < start.
+func (s *IntervalTreap[T, P]) Overlapping(start P, end P) iter.Seq[T] {
+	if s.lessFn(end, start) {
+		panic("provided end must not be lower than start")
+	}
+
+	return func(yield func(T) bool) {
+		if !s.stab(start, yield) {
+			return
+		}
+		// The remaining intervals begin after start, so they overlap exactly when they begin no later than end.
+		node, _ := s.treap.root.lookupLeftmostUnmatch(func(interval T, _ int) bool {
+			return !s.lessFn(start, s.startFn(interval))
+		}, 0)
+		for ; node != nil && !s.lessFn(end, s.startFn(node.value)); node = node.Next() {
+			if !yield(node.value) {
+				return
+			}
+		}
+	}
+}
+
+// Stabbing iterates, in no particular order, over every interval containing point in O(log n + k).
+func (s *IntervalTreap[T, P]) Stabbing(point P) iter.Seq[T] {
+	return s.Overlapping(point, point)
+}
+
+// AnyOverlap returns the first interval in start order that overlaps [start, end], reporting whether one exists.
+// Runs in O(log n). Panics if end < start.
+func (s *IntervalTreap[T, P]) AnyOverlap(start P, end P) (interval T, ok bool) {
+	if s.lessFn(end, start) {
+		panic("provided end must not be lower than start")
+	}
+	if node := s.firstOverlap(s.treap.root, start, end); node != nil {
+		return node.value, true
+	}
+	return interval, false
+}
+
+// Size reports the number of stored intervals.
+func (s *IntervalTreap[T, P]) Size() int {
+	return s.treap.Size()
+}
+
+// Empty reports whether no intervals are stored.
+func (s *IntervalTreap[T, P]) Empty() bool {
+	return s.treap.Empty()
+}
+
+// Clear removes all intervals.
+func (s *IntervalTreap[T, P]) Clear() {
+	s.treap.Clear()
+	clear(s.buckets)
+	clear(s.entries)
+}
+
+// Values iterates over all intervals ordered by start, then end.
+func (s *IntervalTreap[T, P]) Values() iter.Seq[T] {
+	return s.treap.Values()
+}
+
+// stab yields every interval containing point and returns false once yield asks to stop.
+// It walks the search path of point: each bucket on it holds intervals containing the node's start,
+// so the ones also containing point form a prefix of the bucket, by start or by descending end.
+func (s *IntervalTreap[T, P]) stab(point P, yield func(T) bool) bool {
+	for node := s.treap.root; node != nil; {
+		key := s.startFn(node.value)
+		if bucket := s.buckets[node]; bucket != nil {
+			members, reaches := bucket.byStart.Values(), func(interval T) bool {
+				return !s.lessFn(point, s.startFn(interval))
+			}
+			if s.lessFn(key, point) {
+				members, reaches = bucket.byEnd.Values(), func(interval T) bool {
+					return !s.lessFn(s.endFn(interval), point)
+				}
+			}
+			for member := range members {
+				if !reaches(member.value) {
+					break
+				}
+				if !yield(member.value) {
+					return false
+				}
+			}
+		}
+
+		switch {
+		case s.lessFn(point, key):
+			node = node.left
+		case s.lessFn(key, point):
+			node = node.right
+		default:
+			return true
+		}
+	}
+	return true
+}
+
+// firstOverlap returns the first node in start order below node whose interval overlaps [start, end], or nil.
+// Subtrees ending before start are skipped, so only O(log n) nodes are visited.
+func (s *IntervalTreap[T, P]) firstOverlap(node *Node[T], start P, end P) *Node[T] {
+	if node == nil || s.lessFn(s.endFn(node.subtreeAggregate()), start) {
+		return nil
+	}
+	if found := s.firstOverlap(node.left, start, end); found != nil {
+		return found
+	}
+	// This and every following interval begins after end.
+	if s.lessFn(end, s.startFn(node.value)) {
+		return nil
+	}
+	if !s.lessFn(s.endFn(node.value), start) {
+		return node
+	}
+	return s.firstOverlap(node.right, start, end)
+}
+
+// link inserts a node for interval after any equal intervals. Unlike InsertRight it descends only
+// while the nodes on the way outrank the new one and splits the subtree found there, so the
+// ancestors of the new node keep their shape and the buckets filed at them stay valid.
+func (s *IntervalTreap[T, P]) link(interval T) *Node[T] {
+	node := s.treap.newNode(interval, 0)
+	var parent *Node[T]
+	slot := &s.treap.root
+	for *slot != nil && (*slot).heightPriority >= node.heightPriority {
+		parent = *slot
+		if s.treap.lessFn(interval, parent.value) {
+			slot = &parent.left
+		} else {
+			slot = &parent.right
+		}
+	}
+
+	node.left, node.right = (*slot).split(s.treap.condLeq(interval), 0)
+	node.left.safeSetParent(node)
+	node.right.safeSetParent(node)
+	node.parent = parent
+	*slot = node
+	recalcPath(node)
+	return node
+}
+
+// file puts node into the bucket of the topmost node whose start lies within node's interval.
+// Every ancestor of that node starts outside the interval, so the search path of any point
+// of the interval passes through it.
+func (s *IntervalTreap[T, P]) file(node *Node[T]) {
+	start, end := s.startFn(node.value), s.endFn(node.value)
+	home := s.treap.root
+	for {
+		key := s.startFn(home.value)
+		if s.lessFn(key, start) {
+			home = home.right
+		} else if s.lessFn(end, key) {
+			home = home.left
+		} else {
+			break
+		}
+	}
+
+	bucket := s.buckets[home]
+	if bucket == nil {
+		bucket = &intervalBucket[T]{
+			byStart: NewTreapWithRand(s.startsBefore, s.randFn),
+			byEnd:   NewTreapWithRand(s.endsAfter, s.randFn),
+		}
+		s.buckets[home] = bucket
+	}
+	s.entries[node] = bucketEntry[T]{
+		home:    home,
+		byStart: bucket.byStart.At(bucket.byStart.InsertRight(node)),
+		byEnd:   bucket.byEnd.At(bucket.byEnd.InsertRight(node)),
+	}
+}
+
+// unfile takes node out of its bucket, dropping the bucket once empty.
+func (s *IntervalTreap[T, P]) unfile(node *Node[T]) {
+	entry := s.entries[node]
+	delete(s.entries, node)
+	bucket := s.buckets[entry.home]
+	bucket.byStart.EraseNode(entry.byStart)
+	bucket.byEnd.EraseNode(entry.byEnd)
+	if bucket.byStart.Empty() {
+		delete(s.buckets, entry.home)
+	}
+}
+
+// unfileSubtree empties the buckets of root and its descendants and returns the intervals they held.
+// An interval is filed at an ancestor of its own node, so these are at most as many as the subtree's nodes.
+func (s *IntervalTreap[T, P]) unfileSubtree(root *Node[T]) []*Node[T] {
+	var members []*Node[T]
+	var visit func(node *Node[T])
+	visit = func(node *Node[T]) {
+		if node == nil {
+			return
+		}
+		if bucket := s.buckets[node]; bucket != nil {
+			for member := range bucket.byStart.Values() {
+				members = append(members, member)
+				delete(s.entries, member)
+			}
+			delete(s.buckets, node)
+		}
+		visit(node.left)
+		visit(node.right)
+	}
+	visit(root)
+	return members
+}
+
+// startsBefore orders interval nodes by start.
+func (s *IntervalTreap[T, P]) startsBefore(a *Node[T], b *Node[T]) bool {
+	return s.lessFn(s.startFn(a.value), s.startFn(b.value))
+}
+
+// endsAfter orders interval nodes by descending end.
+func (s *IntervalTreap[T, P]) endsAfter(a *Node[T], b *Node[T]) bool {
+	return s.lessFn(s.endFn(b.value), s.endFn(a.value))
+}
@@ -0,0 +1,139 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type span struct {
+	from, to int
+}
+
+func spanStart(s span) int { return s.from }
+func spanEnd(s span) int   { return s.to }
+
+func TestIntervalTreapQueries(t *testing.T) {
+	s := NewAutoOrderIntervalTreapWithRand(spanStart, spanEnd, staticRand(),
+		span{1, 3}, span{2, 6}, span{8, 10}, span{15, 18}, span{5, 5},
+	)
+
+	require.Equal(t, []span{{2, 6}, {5, 5}}, slices.Collect(s.Overlapping(4, 7)))
+	require.Equal(t, []span{{8, 10}}, slices.Collect(s.Overlapping(10, 14)))
+	require.Empty(t, slices.Collect(s.Overlapping(11, 14)))
+	require.ElementsMatch(t, []span{{1, 3}, {2, 6}}, slices.Collect(s.Stabbing(3)))
+	require.ElementsMatch(t, []span{{2, 6}, {5, 5}}, slices.Collect(s.Stabbing(5)))
+
+	first, ok := s.AnyOverlap(0, 100)
+	require.True(t, ok)
+	require.Equal(t, span{1, 3}, first)
+	_, ok = s.AnyOverlap(19, 20)
+	require.False(t, ok)
+
+	require.Panics(t, func() { s.Overlapping(5, 4) })
+	require.Panics(t, func() { s.Insert(span{3, 1}) })
+}
+
+func TestIntervalTreapInsertDelete(t *testing.T) {
+	s := NewAutoOrderIntervalTreapWithRand[span](spanStart, spanEnd, staticRand())
+	require.True(t, s.Empty())
+
+	require.Equal(t, 0, s.Insert(span{4, 9}))
+	require.Equal(t, 0, s.Insert(span{4, 5}))
+	require.Equal(t, 2, s.Insert(span{4, 9}))
+	require.Equal(t, 3, s.Size())
+
+	require.True(t, s.Delete(span{4, 9}))
+	require.False(t, s.Delete(span{4, 6}))
+	require.Equal(t, []span{{4, 5}, {4, 9}}, slices.Collect(s.Values()))
+
+	// The cached latest end must follow deletions.
+	require.True(t, s.Delete(span{4, 9}))
+	require.Empty(t, slices.Collect(s.Stabbing(7)))
+
+	s.Clear()
+	require.Equal(t, 0, s.Size())
+}
+
+func TestIntervalTreapMatchesBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(13, 37))
+	s := NewAutoOrderIntervalTreapWithRand[span](spanStart, spanEnd, staticRand())
+	var all []span
+
+	for i := range 2000 {
+		from := rnd.IntN(1000)
+		iv := span{from, from + rnd.IntN(50)}
+		s.Insert(iv)
+		all = append(all, iv)
+
+		if i%3 == 0 {
+			victim := all[rnd.IntN(len(all))]
+			require.True(t, s.Delete(victim))
+			all = slices.Delete(all, slices.Index(all, victim), slices.Index(all, victim)+1)
+		}
+
+		if i%50 == 0 {
+			a := rnd.IntN(1050)
+			b := a + rnd.IntN(30)
+
+			var expected []span
+			for _, iv := range all {
+				if iv.from <= b && iv.to >= a {
+					expected = append(expected, iv)
+				}
+			}
+			slices.SortFunc(expected, func(x, y span) int {
+				if x.from != y.from {
+					return x.from - y.from
+				}
+				return x.to - y.to
+			})
+
+			require.ElementsMatch(t, expected, slices.Collect(s.Overlapping(a, b)))
+			first, ok := s.AnyOverlap(a, b)
+			require.Equal(t, len(expected) > 0, ok)
+			if ok {
+				require.Equal(t, expected[0], first)
+			}
+			requireIntervalBuckets(t, s)
+		}
+	}
+
+	built := NewAutoOrderIntervalTreapWithRand(spanStart, spanEnd, staticRand(), all...)
+	requireIntervalBuckets(t, built)
+	require.ElementsMatch(t, slices.Collect(s.Stabbing(500)), slices.Collect(built.Stabbing(500)))
+
+	built.Clear()
+	built.Insert(span{1, 2})
+	requireIntervalBuckets(t, built)
+}
+
+// requireIntervalBuckets checks that every interval is filed exactly once,
+// at the topmost node whose start lies within it.
+func requireIntervalBuckets(t *testing.T, s *IntervalTreap[span, int]) {
+	t.Helper()
+	filed := 0
+	for node, bucket := range s.buckets {
+		require.Equal(t, bucket.byStart.Size(), bucket.byEnd.Size())
+		filed += bucket.byStart.Size()
+		for member := range bucket.byStart.Values() {
+			require.Same(t, node, s.entries[member].home)
+		}
+	}
+	require.Equal(t, s.Size(), filed)
+	require.Equal(t, s.Size(), len(s.entries))
+
+	for node := range s.treap.Elements() {
+		home := s.treap.root
+		for home.value.from < node.value.from || home.value.from > node.value.to {
+			if home.value.from < node.value.from {
+				home = home.right
+			} else {
+				home = home.left
+			}
+		}
+		require.Same(t, home, s.entries[node].home)
+	}
+}
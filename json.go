@@ -0,0 +1,51 @@
+package gotreap
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNoOrdering is returned when decoding into a treap that was not created by one of the constructors.
+var ErrNoOrdering = errors.New("gotreap: cannot decode into a treap without an ordering function")
+
+// MarshalJSON encodes the treap as a JSON array of its values in sorted order.
+func (t *Treap[T]) MarshalJSON() ([]byte, error) {
+	values := make([]T, 0, t.Size())
+	for value := range t.Values() {
+		values = append(values, value)
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON replaces the contents of the treap with the values of a JSON array.
+// The treap keeps its ordering, random source and options, so it must be created
+// with a constructor first; use UnmarshalTreapJSON to decode into a new treap instead.
+// Values are sorted on the way in, so the array does not have to be ordered.
+// A JSON null leaves the treap unchanged.
+func (t *Treap[T]) UnmarshalJSON(data []byte) error {
+	if t.lessFn == nil {
+		return ErrNoOrdering
+	}
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+
+	var values []T
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	t.root = t.build(values)
+	t.debugCheck()
+	return nil
+}
+
+// UnmarshalTreapJSON decodes a JSON array into a new treap using lessFn for ordering and opts for optional features.
+func UnmarshalTreapJSON[T any](data []byte, lessFn func(a T, b T) bool, opts Options[T]) (*Treap[T], error) {
+	t := NewTreapWithOptions(lessFn, opts)
+	if err := t.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
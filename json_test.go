@@ -0,0 +1,71 @@
+package gotreap
+
+import (
+	"cmp"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreapMarshalJSON(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 3, 1, 2, 2)
+	data, err := json.Marshal(tr)
+	require.NoError(t, err)
+	require.JSONEq(t, `[1, 2, 2, 3]`, string(data))
+
+	data, err = json.Marshal(NewAutoOrderTreap[string]())
+	require.NoError(t, err)
+	require.Equal(t, `[]`, string(data))
+}
+
+func TestTreapUnmarshalJSONSortsInput(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand[int](staticRand(), 100)
+	require.NoError(t, json.Unmarshal([]byte(`[5, 3, 9, 3]`), tr))
+	requireTreapValues(t, tr, 3, 3, 5, 9)
+
+	require.NoError(t, json.Unmarshal([]byte(`null`), tr))
+	requireTreapValues(t, tr, 3, 3, 5, 9)
+
+	require.Error(t, json.Unmarshal([]byte(`{"a": 1}`), tr))
+	require.Error(t, json.Unmarshal([]byte(`[1, "two"]`), tr))
+	requireTreapValues(t, tr, 3, 3, 5, 9)
+}
+
+func TestTreapUnmarshalJSONKeepsOptions(t *testing.T) {
+	type payload struct {
+		Scores *Treap[int] `json:"scores"`
+	}
+
+	p := payload{Scores: NewTreapWithOptions(cmp.Less[int], Options[int]{RandFn: staticRand(), Aggregate: sumInts})}
+	require.NoError(t, json.Unmarshal([]byte(`{"scores": [4, 1, 3]}`), &p))
+
+	requireTreapValues(t, p.Scores, 1, 3, 4)
+	total, ok := p.Scores.Aggregate()
+	require.True(t, ok)
+	require.Equal(t, 8, total)
+
+	data, err := json.Marshal(p)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"scores": [1, 3, 4]}`, string(data))
+}
+
+func TestTreapUnmarshalJSONWithoutOrdering(t *testing.T) {
+	var p struct {
+		Scores *Treap[int] `json:"scores"`
+	}
+	require.ErrorIs(t, json.Unmarshal([]byte(`{"scores": [1]}`), &p), ErrNoOrdering)
+}
+
+func TestUnmarshalTreapJSON(t *testing.T) {
+	reverse := func(a, b string) bool { return a > b }
+	tr, err := UnmarshalTreapJSON([]byte(`["b", "c", "a"]`), reverse, Options[string]{RandFn: staticRand()})
+	require.NoError(t, err)
+	requireTreapValues(t, tr, "c", "b", "a")
+
+	tr.InsertRight("bb")
+	requireTreapValues(t, tr, "c", "bb", "b", "a")
+
+	_, err = UnmarshalTreapJSON([]byte(`[1]`), reverse, Options[string]{})
+	require.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package gotreap
+
+// The functions below search a treap by key instead of by a full value, so no placeholder value
+// has to be built. cmpFn compares a stored value with the key, returning a negative number,
+// zero or a positive number when the value orders before, together with or after the key.
+// It must agree with the treap's ordering, e.g. compare the ID field a treap of structs is ordered by.
+
+// FindLowerBoundBy returns the first node that does not order before key, along with its index.
+func FindLowerBoundBy[T any, K any](t *Treap[T], key K, cmpFn func(value T, key K) int) (node *Node[T], index int) {
+	return t.root.lookupLeftmostUnmatch(func(nodeValue T, nodeIndex int) bool {
+		return cmpFn(nodeValue, key) < 0
+	}, 0)
+}
+
+// FindUpperBoundBy returns the last node that does not order after key, along with its index.
+func FindUpperBoundBy[T any, K any](t *Treap[T], key K, cmpFn func(value T, key K) int) (node *Node[T], index int) {
+	return t.root.lookupRightmostMatch(func(nodeValue T, nodeIndex int) bool {
+		return cmpFn(nodeValue, key) <= 0
+	}, 0)
+}
+
+// FindBy returns the first node matching key along with its index, or nil and -1 if there is none.
+func FindBy[T any, K any](t *Treap[T], key K, cmpFn func(value T, key K) int) (node *Node[T], index int) {
+	node, index = FindLowerBoundBy(t, key, cmpFn)
+	if node == nil || cmpFn(node.value, key) != 0 {
+		return nil, -1
+	}
+	return node, index
+}
+
+// CountBy reports how many values match key.
+func CountBy[T any, K any](t *Treap[T], key K, cmpFn func(value T, key K) int) int {
+	from, to := equalRangeFunc(t.root, func(nodeValue T) int { return cmpFn(nodeValue, key) })
+	return to - from
+}
+
+// EraseAllBy removes every value matching key and reports how many were deleted.
+func EraseAllBy[T any, K any](t *Treap[T], key K, cmpFn func(value T, key K) int) (erasedCount int) {
+	defer t.debugCheck()
+
+	from, to := equalRangeFunc(t.root, func(nodeValue T) int { return cmpFn(nodeValue, key) })
+	return t.eraseIndexRange(from, to)
+}
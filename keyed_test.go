@@ -0,0 +1,64 @@
+package gotreap
+
+import (
+	"cmp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	id      int
+	payload string
+}
+
+func recordByID(r record, id int) int {
+	return cmp.Compare(r.id, id)
+}
+
+func newRecords() *Treap[record] {
+	return NewTreapCmpWithRand(func(a, b record) int { return cmp.Compare(a.id, b.id) }, staticRand(),
+		record{1, "a"}, record{3, "b"}, record{3, "c"}, record{7, "d"},
+	)
+}
+
+func TestKeyedLookups(t *testing.T) {
+	tr := newRecords()
+
+	node, idx := FindLowerBoundBy(tr, 2, recordByID)
+	require.Equal(t, 3, node.Value().id)
+	require.Equal(t, 1, idx)
+
+	node, idx = FindUpperBoundBy(tr, 3, recordByID)
+	require.Equal(t, 3, node.Value().id)
+	require.Equal(t, 2, idx)
+
+	node, _ = FindUpperBoundBy(tr, 0, recordByID)
+	require.Nil(t, node)
+	node, _ = FindLowerBoundBy(tr, 8, recordByID)
+	require.Nil(t, node)
+
+	node, idx = FindBy(tr, 3, recordByID)
+	require.Equal(t, 1, idx)
+	require.Equal(t, 3, node.Value().id)
+	node, idx = FindBy(tr, 4, recordByID)
+	require.Nil(t, node)
+	require.Equal(t, -1, idx)
+
+	require.Equal(t, 2, CountBy(tr, 3, recordByID))
+	require.Equal(t, 0, CountBy(tr, 5, recordByID))
+	require.Equal(t, 0, CountBy(NewTreapCmp(func(a, b record) int { return a.id - b.id }), 1, recordByID))
+}
+
+func TestEraseAllBy(t *testing.T) {
+	tr := newRecords()
+
+	require.Equal(t, 2, EraseAllBy(tr, 3, recordByID))
+	require.Equal(t, 0, EraseAllBy(tr, 3, recordByID))
+	requireTreapValues(t, tr, record{1, "a"}, record{7, "d"})
+
+	// Works on treaps built from a lessFn as well.
+	byLess := NewTreapWithRand(func(a, b record) bool { return a.id < b.id }, staticRand(), record{2, "x"}, record{2, "y"}, record{4, "z"})
+	require.Equal(t, 2, EraseAllBy(byLess, 2, recordByID))
+	requireTreapValues(t, byLess, record{4, "z"})
+}
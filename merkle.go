@@ -0,0 +1,215 @@
+package gotreap
+
+import (
+	"iter"
+	"math/bits"
+)
+
+// hashModulus is the Mersenne prime 2^61-1 the content hash is computed modulo.
+const hashModulus = 1<<61 - 1
+
+// hashBase is the polynomial base of the content hash.
+const hashBase = 0x16a09e667f3bcc9
+
+// seqHash is the polynomial hash of a sequence of values: sum is the sum of h(v_i)*B^(n-1-i)
+// and pow is B^n, both modulo hashModulus. It only depends on the in-order contents,
+// so two trees holding the same sequence hash identically regardless of their shape.
+type seqHash struct {
+	sum uint64
+	pow uint64
+}
+
+// emptySeqHash is the hash of the empty sequence.
+var emptySeqHash = seqHash{sum: 0, pow: 1}
+
+// mulMod returns a*b modulo hashModulus for a, b < hashModulus.
+func mulMod(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	// 2^64 = 8 (mod 2^61-1), so the product folds into a value below 3*2^61.
+	r := (lo & hashModulus) + (lo >> 61) + (hi << 3)
+	r = (r & hashModulus) + (r >> 61)
+	if r >= hashModulus {
+		r -= hashModulus
+	}
+	return r
+}
+
+// addMod returns a+b modulo hashModulus for a, b < hashModulus.
+func addMod(a, b uint64) uint64 {
+	r := a + b
+	if r >= hashModulus {
+		r -= hashModulus
+	}
+	return r
+}
+
+// valueSeqHash returns the hash of the single-element sequence holding a value hashed to h.
+// h is mixed first: the polynomial is linear in the value hashes, so weak hashes such as
+// the identity would otherwise make different sequences collide. The splitmix64 increment
+// keeps a zero hash from staying zero.
+func valueSeqHash(h uint64) seqHash {
+	return seqHash{sum: mix64(h+0x9e3779b97f4a7c15) % hashModulus, pow: hashBase}
+}
+
+// digest folds both components into a single value, so sequences of different lengths with
+// the same sum still differ.
+func (a seqHash) digest() uint64 {
+	return mix64(a.sum ^ mix64(a.pow))
+}
+
+// concat returns the hash of the sequence a followed by b.
+func (a seqHash) concat(b seqHash) seqHash {
+	return seqHash{sum: addMod(mulMod(a.sum, b.pow), b.sum), pow: mulMod(a.pow, b.pow)}
+}
+
+// subtreeHash returns the cached content hash of the subtree rooted at t.
+// Only valid for trees that maintain content hashes.
+func (t *Node[T]) subtreeHash() seqHash {
+	return *t.ext.contentHash
+}
+
+// recalcHash recomputes the cached content hash from the children's hashes and t.value.
+func (t *Node[T]) recalcHash() {
+	h := valueSeqHash(t.ext.traits.hash(t.value))
+	if t.left != nil {
+		h = t.left.subtreeHash().concat(h)
+	}
+	if t.right != nil {
+		h = h.concat(t.right.subtreeHash())
+	}
+	*t.ext.contentHash = h
+}
+
+// hashIndexRange returns the content hash of the values whose indices fall in [from, to)
+// within the subtree rooted at t, whose first element has index indexOffset.
+func (t *Node[T]) hashIndexRange(from, to int, indexOffset int) seqHash {
+	if t == nil || to <= indexOffset || indexOffset+t.size <= from {
+		return emptySeqHash
+	}
+	if from <= indexOffset && indexOffset+t.size <= to {
+		return t.subtreeHash()
+	}
+
+	centralIndexOffset := indexOffset + t.left.safeSize()
+	result := t.left.hashIndexRange(from, to, indexOffset)
+	if from <= centralIndexOffset && centralIndexOffset < to {
+		result = result.concat(valueSeqHash(t.ext.traits.hash(t.value)))
+	}
+	return result.concat(t.right.hashIndexRange(from, to, centralIndexOffset+1))
+}
+
+// requireContentHash panics unless the treap was constructed with a content hash function.
+func (t *Treap[T]) requireContentHash() {
+	if t.traits == nil || t.traits.hash == nil {
+		panic("treap has no content hash configured, use Options.ContentHash")
+	}
+}
+
+// contentHash returns the content hash of the whole treap.
+func (t *Treap[T]) contentHash() seqHash {
+	if t.root == nil {
+		return emptySeqHash
+	}
+	return t.root.subtreeHash()
+}
+
+// Hash returns a hash of the in-order contents of the treap, including their number, in O(1).
+// Treaps holding the same sequence of values hash identically whatever their shape or history,
+// provided they use the same Options.ContentHash.
+// Panics if the treap has no content hash configured.
+func (t *Treap[T]) Hash() uint64 {
+	t.requireContentHash()
+	return t.contentHash().digest()
+}
+
+// Equal reports whether t and other hold the same sequence of values in O(1) by comparing
+// sizes and content hashes. Like any hash comparison it may report a false positive on a
+// collision, which is vanishingly unlikely as value hashes are mixed before being combined.
+// Both treaps must use the same content hash function.
+// Panics if either treap has no content hash configured.
+func (t *Treap[T]) Equal(other *Treap[T]) bool {
+	t.requireContentHash()
+	other.requireContentHash()
+	return t.Size() == other.Size() && t.contentHash() == other.contentHash()
+}
+
+// commonRun returns the length of the longest run, at most limit elements, that starts at
+// index i in t and at index j in other and has the same contents in both.
+// It gallops over range hashes, so identical stretches cost O(log² n) regardless of their length.
+func (t *Treap[T]) commonRun(i int, other *Treap[T], j int, limit int) int {
+	same := func(n int) bool {
+		return t.root.hashIndexRange(i, i+n, 0) == other.root.hashIndexRange(j, j+n, 0)
+	}
+
+	low, high := 0, 1
+	for high <= limit && same(high) {
+		low, high = high, high*2
+	}
+	high = min(high, limit+1)
+	// Invariant: the first low elements match and the first high don't (or exceed limit).
+	for high-low > 1 {
+		mid := low + (high-low)/2
+		if same(mid) {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return low
+}
+
+// Diff returns an iterator over the values of t and other that are not shared between them,
+// in order. The boolean is true for values present only in t and false for values present
+// only in other. Identical stretches of both treaps are skipped by comparing content hashes,
+// so the cost grows with the number of differences rather than the size of the treaps.
+// Values that are equal in the order but hash differently are reported pairwise, by their
+// position among equal values. Both treaps must use the same order and content hash function,
+// and must not be modified during iteration.
+// See Changes for per-value occurrence counts instead.
+// Panics if either treap has no content hash configured.
+func (t *Treap[T]) Diff(other *Treap[T]) iter.Seq2[T, bool] {
+	t.requireContentHash()
+	other.requireContentHash()
+
+	return func(yield func(T, bool) bool) {
+		i, j := 0, 0
+		n, m := t.Size(), other.Size()
+		for i < n && j < m {
+			run := t.commonRun(i, other, j, min(n-i, m-j))
+			i, j = i+run, j+run
+			if i == n || j == m {
+				break
+			}
+
+			a, b := t.At(i).value, other.At(j).value
+			switch {
+			case t.lessFn(a, b):
+				if !yield(a, true) {
+					return
+				}
+				i++
+			case t.lessFn(b, a):
+				if !yield(b, false) {
+					return
+				}
+				j++
+			default:
+				if !yield(a, true) || !yield(b, false) {
+					return
+				}
+				i, j = i+1, j+1
+			}
+		}
+
+		for _, value := range t.ValuesBetweenIndices(i, n) {
+			if !yield(value, true) {
+				return
+			}
+		}
+		for _, value := range other.ValuesBetweenIndices(j, m) {
+			if !yield(value, false) {
+				return
+			}
+		}
+	}
+}
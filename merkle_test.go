@@ -0,0 +1,173 @@
+package gotreap
+
+import (
+	"cmp"
+	"math/big"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newHashed(values ...int) *Treap[int] {
+	return NewTreapCmpWithOptions(cmp.Compare[int], Options[int]{ContentHash: CodecHash[int](IntCodec[int]{}, 1)}, values...)
+}
+
+type diffEntry struct {
+	Value  int
+	OnlyIn bool
+}
+
+func collectDiff(a, b *Treap[int]) []diffEntry {
+	var result []diffEntry
+	for value, onlyInA := range a.Diff(b) {
+		result = append(result, diffEntry{value, onlyInA})
+	}
+	return result
+}
+
+func TestHashIndependentOfShape(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(3, 4))
+	values := make([]int, 300)
+	for i := range values {
+		values[i] = rnd.IntN(100)
+	}
+
+	bulk := newHashed(values...)
+	incremental := newHashed()
+	for _, v := range values {
+		incremental.InsertRight(v)
+	}
+	require.True(t, bulk.Equal(incremental))
+	require.Equal(t, bulk.Hash(), incremental.Hash())
+
+	incremental.EraseLeftmost(values[0], 1)
+	require.False(t, bulk.Equal(incremental))
+	incremental.InsertLeft(values[0])
+	require.True(t, bulk.Equal(incremental))
+
+	left, right := incremental.SplitBefore(50)
+	require.True(t, bulk.Equal(Merge(left, right)))
+
+	require.Equal(t, newHashed().Hash(), newHashed().Hash())
+	require.NotEqual(t, newHashed().Hash(), newHashed(0).Hash())
+	require.True(t, newHashed().Equal(newHashed()))
+	require.False(t, newHashed(1, 2).Equal(newHashed(1, 3)))
+	require.False(t, newHashed(0).Equal(newHashed()))
+}
+
+func TestHashWithWeakValueHash(t *testing.T) {
+	identity := func(v int) uint64 { return uint64(v) }
+	newWeak := func(values ...int) *Treap[int] {
+		return NewTreapCmpWithOptions(cmp.Compare[int], Options[int]{ContentHash: identity}, values...)
+	}
+
+	require.NotEqual(t, newWeak(0, 5).Hash(), newWeak(5).Hash())
+	require.NotEqual(t, newWeak(0).Hash(), newWeak().Hash())
+	require.NotEqual(t, newWeak(1, 2).Hash(), newWeak(0, hashBase+2).Hash())
+	require.False(t, newWeak(1, 2).Equal(newWeak(0, hashBase+2)))
+	require.False(t, newWeak(0).Equal(newWeak()))
+	require.True(t, newWeak(3, 1, 2).Equal(newWeak(1, 2, 3)))
+}
+
+func TestHashWithAggregateAndCanonical(t *testing.T) {
+	opts := Options[int]{
+		Aggregate:    func(a, b int) int { return a + b },
+		PriorityHash: CodecHash[int](IntCodec[int]{}, 2),
+		ContentHash:  CodecHash[int](IntCodec[int]{}, 2),
+	}
+	a := NewTreapCmpWithOptions(cmp.Compare[int], opts, 5, 1, 3)
+	b := NewTreapCmpWithOptions(cmp.Compare[int], opts, 3, 5, 1)
+	require.True(t, a.Equal(b))
+	require.Equal(t, shapeOf(a.root), shapeOf(b.root))
+	sum, _ := a.Aggregate()
+	require.Equal(t, 9, sum)
+
+	a.ReplaceValue(a.At(0), 4)
+	require.False(t, a.Equal(b))
+	require.NoError(t, a.Validate())
+}
+
+func TestHashRequiresOption(t *testing.T) {
+	tr := NewAutoOrderTreap(1, 2)
+	require.Panics(t, func() { tr.Hash() })
+	require.Panics(t, func() { tr.Equal(newHashed(1, 2)) })
+	require.Panics(t, func() { newHashed().Diff(tr) })
+}
+
+func TestDiff(t *testing.T) {
+	a := newHashed(1, 2, 3, 4, 5, 7, 9)
+	b := newHashed(2, 3, 4, 5, 6, 7, 10, 11)
+	require.Equal(t, []diffEntry{{1, true}, {6, false}, {9, true}, {10, false}, {11, false}}, collectDiff(a, b))
+	require.Empty(t, collectDiff(a, newHashed(1, 2, 3, 4, 5, 7, 9)))
+	require.Equal(t, []diffEntry{{1, true}, {1, true}}, collectDiff(newHashed(1, 1, 2), newHashed(2)))
+	require.Equal(t, []diffEntry{{3, false}}, collectDiff(newHashed(), newHashed(3)))
+
+	// Early termination.
+	for range a.Diff(b) {
+		break
+	}
+}
+
+func TestDiffEqualInOrderButDifferentContent(t *testing.T) {
+	type entry struct {
+		key   int
+		value string
+	}
+	opts := Options[entry]{ContentHash: func(e entry) uint64 {
+		return uint64(e.key)*31 + uint64(len(e.value))
+	}}
+	byKey := func(a, b entry) int { return cmp.Compare(a.key, b.key) }
+	a := NewTreapCmpWithOptions(byKey, opts, entry{1, "a"}, entry{2, "b"})
+	b := NewTreapCmpWithOptions(byKey, opts, entry{1, "a"}, entry{2, "bb"})
+
+	var got []entry
+	var sides []bool
+	for e, onlyInA := range a.Diff(b) {
+		got = append(got, e)
+		sides = append(sides, onlyInA)
+	}
+	require.Equal(t, []entry{{2, "b"}, {2, "bb"}}, got)
+	require.Equal(t, []bool{true, false}, sides)
+}
+
+func TestDiffMatchesBruteForce(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(5, 6))
+	for range 50 {
+		var left, right []int
+		for v := range 400 {
+			switch rnd.IntN(20) {
+			case 0:
+				left = append(left, v)
+			case 1:
+				right = append(right, v)
+			default:
+				left = append(left, v)
+				right = append(right, v)
+			}
+		}
+
+		var expected []diffEntry
+		for _, v := range left {
+			if !slices.Contains(right, v) {
+				expected = append(expected, diffEntry{v, true})
+			}
+		}
+		for _, v := range right {
+			if !slices.Contains(left, v) {
+				expected = append(expected, diffEntry{v, false})
+			}
+		}
+		slices.SortStableFunc(expected, func(a, b diffEntry) int { return cmp.Compare(a.Value, b.Value) })
+
+		require.Equal(t, expected, collectDiff(newHashed(left...), newHashed(right...)))
+	}
+}
+
+func TestMulMod(t *testing.T) {
+	for _, pair := range [][2]uint64{{0, 5}, {1, hashModulus - 1}, {hashModulus - 1, hashModulus - 1}, {hashBase, 123456789}} {
+		want := new(big.Int).Mod(new(big.Int).Mul(new(big.Int).SetUint64(pair[0]), new(big.Int).SetUint64(pair[1])), big.NewInt(hashModulus))
+		require.Equal(t, want.Uint64(), mulMod(pair[0], pair[1]))
+	}
+}
@@ -0,0 +1,465 @@
+package gotreap
+
+import (
+	"cmp"
+	"iter"
+	"math/rand/v2"
+	"sort"
+)
+
+// runNode is a multiset tree node holding a run of count equal values.
+// It has no parent pointer and no per-feature fields, keeping heavy histograms compact.
+type runNode[T any] struct {
+	value    T
+	count    int
+	size     int // total multiplicity of the subtree
+	runs     int // number of runs in the subtree
+	priority int
+	left     *runNode[T]
+	right    *runNode[T]
+}
+
+// safeSize returns the total multiplicity of the subtree, treating nil as empty.
+func (t *runNode[T]) safeSize() int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+// safeRuns returns the number of runs in the subtree, treating nil as empty.
+func (t *runNode[T]) safeRuns() int {
+	if t == nil {
+		return 0
+	}
+	return t.runs
+}
+
+// recalc recomputes the cached size and run count of t from its children.
+func (t *runNode[T]) recalc() {
+	t.size = t.left.safeSize() + t.count + t.right.safeSize()
+	t.runs = t.left.safeRuns() + 1 + t.right.safeRuns()
+}
+
+// mergeRuns combines two priority-ordered subtrees preserving in-order sequence.
+func mergeRuns[T any](left *runNode[T], right *runNode[T]) *runNode[T] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	if left.priority >= right.priority {
+		left.right = mergeRuns(left.right, right)
+		left.recalc()
+		return left
+	}
+	right.left = mergeRuns(left, right.left)
+	right.recalc()
+	return right
+}
+
+// splitRuns partitions the subtree into the runs whose value satisfies the monotone leftCond and the rest.
+func splitRuns[T any](t *runNode[T], leftCond func(value T) bool) (left, right *runNode[T]) {
+	if t == nil {
+		return nil, nil
+	}
+
+	if leftCond(t.value) {
+		t.right, right = splitRuns(t.right, leftCond)
+		t.recalc()
+		return t, right
+	}
+	left, t.left = splitRuns(t.left, leftCond)
+	t.recalc()
+	return left, t
+}
+
+// splitRunsAt partitions the subtree into its first n elements and the rest,
+// dividing the run that straddles position n between both sides.
+func splitRunsAt[T any](t *runNode[T], n int) (left, right *runNode[T]) {
+	if t == nil {
+		return nil, nil
+	}
+
+	leftSize := t.left.safeSize()
+	switch {
+	case n <= leftSize:
+		left, t.left = splitRunsAt(t.left, n)
+		t.recalc()
+		return left, t
+	case n >= leftSize+t.count:
+		t.right, right = splitRunsAt(t.right, n-leftSize-t.count)
+		t.recalc()
+		return t, right
+	}
+
+	// The tail of the run keeps t's priority and right subtree, so both halves stay heap-ordered.
+	tail := &runNode[T]{value: t.value, count: leftSize + t.count - n, priority: t.priority, right: t.right}
+	t.count = n - leftSize
+	t.right = nil
+	t.recalc()
+	tail.recalc()
+	return t, tail
+}
+
+// walkRuns calls yield for every run of the subtree in order and reports whether the walk completed.
+func walkRuns[T any](t *runNode[T], yield func(value T, count int) bool) bool {
+	if t == nil {
+		return true
+	}
+	return walkRuns(t.left, yield) && yield(t.value, t.count) && walkRuns(t.right, yield)
+}
+
+// Multiset is an ordered multiset that stores each distinct value once together with its
+// number of occurrences. Inserting a value that is already present only bumps its count,
+// so heavily repeated values cost one node in total. Positions count every occurrence,
+// so At, Index, Cut and CountRange behave as on a Treap holding the same values.
+// Equal values are indistinguishable: a run keeps the first value inserted into it.
+type Multiset[T any] struct {
+	lessFn func(a T, b T) bool
+	randFn func() int
+	root   *runNode[T]
+}
+
+// NewAutoOrderMultiset builds a multiset using the natural ordering for type T.
+func NewAutoOrderMultiset[T cmp.Ordered](values ...T) *Multiset[T] {
+	return NewMultiset(cmp.Less[T], values...)
+}
+
+// NewAutoOrderMultisetWithRand builds a multiset using the natural ordering for type T and a custom random function.
+func NewAutoOrderMultisetWithRand[T cmp.Ordered](randFn func() int, values ...T) *Multiset[T] {
+	return NewMultisetWithRand(cmp.Less[T], randFn, values...)
+}
+
+// NewMultiset constructs a multiset using lessFn for ordering and optionally inserts values.
+func NewMultiset[T any](lessFn func(a T, b T) bool, values ...T) *Multiset[T] {
+	return NewMultisetWithRand(lessFn, rand.Int, values...)
+}
+
+// NewMultisetWithRand constructs a multiset using lessFn for ordering, randFn for tree balancing, and optionally inserts values.
+func NewMultisetWithRand[T any](lessFn func(a T, b T) bool, randFn func() int, values ...T) *Multiset[T] {
+	if lessFn == nil {
+		panic("lessFn must not be nil")
+	}
+	if randFn == nil {
+		panic("randFn must not be nil")
+	}
+
+	m := &Multiset[T]{lessFn: lessFn, randFn: randFn}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return lessFn(values[i], values[j])
+	})
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && !lessFn(values[i], values[j]) {
+			j++
+		}
+		m.root = mergeRuns(m.root, m.newRun(values[i], j-i))
+		i = j
+	}
+
+	return m
+}
+
+// withRoot creates a multiset sharing m's configuration with the provided root.
+func (m *Multiset[T]) withRoot(root *runNode[T]) *Multiset[T] {
+	return &Multiset[T]{lessFn: m.lessFn, randFn: m.randFn, root: root}
+}
+
+// newRun creates a detached node holding count occurrences of value.
+func (m *Multiset[T]) newRun(value T, count int) *runNode[T] {
+	node := &runNode[T]{value: value, count: count, priority: m.randFn()}
+	node.recalc()
+	return node
+}
+
+// find returns the run holding value, or nil, along with the index of its first element,
+// or of the position value would be inserted at.
+func (m *Multiset[T]) find(value T) (node *runNode[T], index int) {
+	for cur := m.root; cur != nil; {
+		switch {
+		case m.lessFn(value, cur.value):
+			cur = cur.left
+		case m.lessFn(cur.value, value):
+			index += cur.left.safeSize() + cur.count
+			cur = cur.right
+		default:
+			return cur, index + cur.left.safeSize()
+		}
+	}
+	return nil, index
+}
+
+// resize adds delta to the count of the existing run holding value and to every size on the path to it.
+func (m *Multiset[T]) resize(value T, delta int) {
+	for cur := m.root; ; {
+		cur.size += delta
+		switch {
+		case m.lessFn(value, cur.value):
+			cur = cur.left
+		case m.lessFn(cur.value, value):
+			cur = cur.right
+		default:
+			cur.count += delta
+			return
+		}
+	}
+}
+
+// prefixLen returns how many elements have a value satisfying the monotone leftCond.
+func (m *Multiset[T]) prefixLen(leftCond func(value T) bool) int {
+	count := 0
+	for cur := m.root; cur != nil; {
+		if leftCond(cur.value) {
+			count += cur.left.safeSize() + cur.count
+			cur = cur.right
+		} else {
+			cur = cur.left
+		}
+	}
+	return count
+}
+
+// less returns a predicate that is true for values less than value.
+func (m *Multiset[T]) less(value T) func(T) bool {
+	return func(v T) bool { return m.lessFn(v, value) }
+}
+
+// leq returns a predicate that is true for values less than or equal to value.
+func (m *Multiset[T]) leq(value T) func(T) bool {
+	return func(v T) bool { return !m.lessFn(value, v) }
+}
+
+// insert adds n > 0 occurrences of value and returns its run along with the index of the run's first element.
+func (m *Multiset[T]) insert(value T, n int) (node *runNode[T], index int) {
+	node, index = m.find(value)
+	if node != nil {
+		m.resize(value, n)
+		return node, index
+	}
+
+	node = m.newRun(value, n)
+	less, greater := splitRuns(m.root, m.less(value))
+	m.root = mergeRuns(mergeRuns(less, node), greater)
+	return node, index
+}
+
+// InsertN adds n occurrences of value and returns the index of the first one added,
+// which follows any occurrences already present. Panics if n is negative.
+func (m *Multiset[T]) InsertN(value T, n int) (index int) {
+	if n < 0 {
+		panic("n must not be negative")
+	}
+	if n == 0 {
+		node, index := m.find(value)
+		if node != nil {
+			index += node.count
+		}
+		return index
+	}
+
+	node, index := m.insert(value, n)
+	return index + node.count - n
+}
+
+// InsertLeft adds one occurrence of value and returns the index of the first occurrence.
+func (m *Multiset[T]) InsertLeft(value T) (index int) {
+	_, index = m.insert(value, 1)
+	return index
+}
+
+// InsertRight adds one occurrence of value and returns the index of the last occurrence.
+func (m *Multiset[T]) InsertRight(value T) (index int) {
+	node, index := m.insert(value, 1)
+	return index + node.count - 1
+}
+
+// erase removes up to n occurrences of value, or all of them when n is negative.
+func (m *Multiset[T]) erase(value T, n int) (erasedCount int) {
+	node, _ := m.find(value)
+	if node == nil {
+		return 0
+	}
+	if n >= 0 && n < node.count {
+		m.resize(value, -n)
+		return n
+	}
+
+	erasedCount = node.count
+	less, rest := splitRuns(m.root, m.less(value))
+	_, greater := splitRuns(rest, m.leq(value))
+	m.root = mergeRuns(less, greater)
+	return erasedCount
+}
+
+// EraseAll removes every occurrence of value and reports how many were deleted.
+func (m *Multiset[T]) EraseAll(value T) (erasedCount int) {
+	return m.erase(value, -1)
+}
+
+// EraseLeftmost removes up to n occurrences of value by decrementing its count.
+// As equal values are indistinguishable, it is equivalent to EraseRightmost.
+func (m *Multiset[T]) EraseLeftmost(value T, n int) (erasedCount int) {
+	return m.erase(value, n)
+}
+
+// EraseRightmost removes up to n occurrences of value by decrementing its count.
+// As equal values are indistinguishable, it is equivalent to EraseLeftmost.
+func (m *Multiset[T]) EraseRightmost(value T, n int) (erasedCount int) {
+	return m.erase(value, n)
+}
+
+// requireValidRange panics on the value bounds rejected by CountRange.
+func (m *Multiset[T]) requireValidRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) {
+	if m.lessFn(endValue, startValue) {
+		panic("provided endValue must not be lower than startValue")
+	}
+	if !m.lessFn(startValue, endValue) && (!inclusiveStart || !inclusiveEnd) {
+		panic("when startValue == endValue, both start and end must be inclusive")
+	}
+}
+
+// rangeConds returns the split conditions for the values before the range and the values up to its end.
+func (m *Multiset[T]) rangeConds(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) (before, upToEnd func(T) bool) {
+	m.requireValidRange(startValue, inclusiveStart, endValue, inclusiveEnd)
+
+	before, upToEnd = m.leq(startValue), m.less(endValue)
+	if inclusiveStart {
+		before = m.less(startValue)
+	}
+	if inclusiveEnd {
+		upToEnd = m.leq(endValue)
+	}
+	return before, upToEnd
+}
+
+// EraseRange removes values between startValue and endValue.
+// Each bound is removed only when its corresponding inclusive flag is true, and the method reports how many values were erased.
+// Panics if endValue < startValue, or if startValue == endValue with non-inclusive bounds.
+func (m *Multiset[T]) EraseRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) (erasedCount int) {
+	before, upToEnd := m.rangeConds(startValue, inclusiveStart, endValue, inclusiveEnd)
+
+	leftRemainder, rest := splitRuns(m.root, before)
+	toErase, rightRemainder := splitRuns(rest, upToEnd)
+	m.root = mergeRuns(leftRemainder, rightRemainder)
+
+	return toErase.safeSize()
+}
+
+// CountRange returns how many values fall between startValue and endValue.
+// Each bound contributes to the count only when its inclusive flag is true, so exclusive flags treat that bound as open.
+// Panics if endValue < startValue, or if startValue == endValue with non-inclusive bounds.
+func (m *Multiset[T]) CountRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) int {
+	before, upToEnd := m.rangeConds(startValue, inclusiveStart, endValue, inclusiveEnd)
+	return max(0, m.prefixLen(upToEnd)-m.prefixLen(before))
+}
+
+// Count reports the number of occurrences of value in the multiset.
+func (m *Multiset[T]) Count(value T) int {
+	node, _ := m.find(value)
+	if node == nil {
+		return 0
+	}
+	return node.count
+}
+
+// Index returns the index of the first occurrence of value, or false if it is absent.
+func (m *Multiset[T]) Index(value T) (index int, ok bool) {
+	node, index := m.find(value)
+	return index, node != nil
+}
+
+// At returns the value located at the provided index, or false if it is out of range.
+// Supports negative indexing where -1 refers to the last element.
+func (m *Multiset[T]) At(index int) (value T, ok bool) {
+	sz := m.root.safeSize()
+	if index < -sz || index >= sz {
+		return value, false
+	}
+	if index < 0 {
+		index = sz + index
+	}
+
+	for cur := m.root; ; {
+		leftSize := cur.left.safeSize()
+		switch {
+		case index < leftSize:
+			cur = cur.left
+		case index < leftSize+cur.count:
+			return cur.value, true
+		default:
+			index -= leftSize + cur.count
+			cur = cur.right
+		}
+	}
+}
+
+// split divides the multiset into two new multisets and clears the receiver.
+func (m *Multiset[T]) split(left, right *runNode[T]) (*Multiset[T], *Multiset[T]) {
+	m.root = nil
+	return m.withRoot(left), m.withRoot(right)
+}
+
+// SplitBefore splits the multiset at the first value not less than value.
+func (m *Multiset[T]) SplitBefore(value T) (left *Multiset[T], right *Multiset[T]) {
+	return m.split(splitRuns(m.root, m.less(value)))
+}
+
+// SplitAfter splits the multiset after the last value less than or equal to value.
+func (m *Multiset[T]) SplitAfter(value T) (left *Multiset[T], right *Multiset[T]) {
+	return m.split(splitRuns(m.root, m.leq(value)))
+}
+
+// Cut splits the multiset into the first n elements and the remainder, dividing a run if needed.
+// If n is negative, cuts from the end (e.g., Cut(-2) returns all but the last 2 elements as left).
+// If the computed position is negative, everything goes to right.
+func (m *Multiset[T]) Cut(n int) (left *Multiset[T], right *Multiset[T]) {
+	if n < 0 {
+		n = max(0, m.root.safeSize()+n)
+	}
+	return m.split(splitRunsAt(m.root, n))
+}
+
+// Size reports the number of elements stored in the multiset, counting every occurrence.
+func (m *Multiset[T]) Size() int {
+	return m.root.safeSize()
+}
+
+// Distinct reports the number of distinct values stored in the multiset.
+func (m *Multiset[T]) Distinct() int {
+	return m.root.safeRuns()
+}
+
+// Empty reports whether the multiset contains no elements.
+func (m *Multiset[T]) Empty() bool {
+	return m.root == nil
+}
+
+// Clear removes every element from the multiset.
+func (m *Multiset[T]) Clear() {
+	m.root = nil
+}
+
+// Runs returns an iterator over the distinct values in order, each with its number of occurrences.
+func (m *Multiset[T]) Runs() iter.Seq2[T, int] {
+	return func(yield func(T, int) bool) {
+		walkRuns(m.root, yield)
+	}
+}
+
+// Values returns an iterator over every element in order, repeating each value as many times as it occurs.
+func (m *Multiset[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		walkRuns(m.root, func(value T, count int) bool {
+			for range count {
+				if !yield(value) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireMultisetInvariants checks heap order, run ordering and cached sizes of the multiset.
+func requireMultisetInvariants[T any](t *testing.T, m *Multiset[T]) {
+	t.Helper()
+	var check func(node *runNode[T]) (size, runs int)
+	check = func(node *runNode[T]) (size, runs int) {
+		if node == nil {
+			return 0, 0
+		}
+		require.Positive(t, node.count)
+		for _, child := range []*runNode[T]{node.left, node.right} {
+			if child != nil {
+				require.LessOrEqual(t, child.priority, node.priority)
+			}
+		}
+		leftSize, leftRuns := check(node.left)
+		rightSize, rightRuns := check(node.right)
+		require.Equal(t, leftSize+node.count+rightSize, node.size)
+		require.Equal(t, leftRuns+1+rightRuns, node.runs)
+		return node.size, node.runs
+	}
+	check(m.root)
+
+	var prev T
+	first := true
+	for value := range m.Runs() {
+		if !first {
+			require.True(t, m.lessFn(prev, value), "runs must be strictly ascending")
+		}
+		prev, first = value, false
+	}
+}
+
+func TestMultisetBasics(t *testing.T) {
+	m := NewAutoOrderMultisetWithRand(staticRand(), 5, 1, 5, 3, 5)
+	require.Equal(t, []int{1, 3, 5, 5, 5}, slices.Collect(m.Values()))
+	require.Equal(t, 5, m.Size())
+	require.Equal(t, 3, m.Distinct())
+
+	require.Equal(t, 2, m.InsertLeft(5))
+	require.Equal(t, 6, m.InsertRight(5))
+	require.Equal(t, 7, m.InsertN(5, 1000))
+	require.Equal(t, 1005, m.Count(5))
+	require.Equal(t, 3, m.Distinct())
+	require.Equal(t, 1, m.InsertN(2, 0))
+	require.Equal(t, 3, m.Distinct())
+
+	require.Equal(t, 1, m.InsertRight(2))
+	index, ok := m.Index(3)
+	require.True(t, ok)
+	require.Equal(t, 2, index)
+	index, ok = m.Index(4)
+	require.False(t, ok)
+	require.Equal(t, 3, index)
+
+	value, ok := m.At(500)
+	require.True(t, ok)
+	require.Equal(t, 5, value)
+	value, ok = m.At(-m.Size())
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+	_, ok = m.At(m.Size())
+	require.False(t, ok)
+
+	require.Equal(t, 1000, m.EraseLeftmost(5, 1000))
+	require.Equal(t, 5, m.Count(5))
+	require.Equal(t, 5, m.EraseRightmost(5, -1))
+	require.Zero(t, m.Count(5))
+	require.Equal(t, 3, m.Distinct())
+	require.Equal(t, []int{1, 2, 3}, slices.Collect(m.Values()))
+	require.Equal(t, 0, m.EraseAll(42))
+	requireMultisetInvariants(t, m)
+
+	m.Clear()
+	require.True(t, m.Empty())
+	require.Panics(t, func() { m.InsertN(1, -1) })
+	require.Panics(t, func() { NewMultiset[int](nil) })
+}
+
+func TestMultisetCutSplitsRuns(t *testing.T) {
+	m := NewAutoOrderMultiset[int]()
+	m.InsertN(1, 3)
+	m.InsertN(2, 4)
+	m.InsertN(3, 2)
+
+	left, right := m.Cut(5)
+	require.True(t, m.Empty())
+	require.Equal(t, []int{1, 1, 1, 2, 2}, slices.Collect(left.Values()))
+	require.Equal(t, []int{2, 2, 3, 3}, slices.Collect(right.Values()))
+	requireMultisetInvariants(t, left)
+	requireMultisetInvariants(t, right)
+
+	left.InsertRight(2)
+	require.Equal(t, 3, left.Count(2))
+	require.Equal(t, 2, left.Distinct())
+
+	head, tail := right.Cut(-1)
+	require.Equal(t, []int{2, 2, 3}, slices.Collect(head.Values()))
+	require.Equal(t, []int{3}, slices.Collect(tail.Values()))
+
+	before, after := head.SplitBefore(3)
+	require.Equal(t, 2, before.Size())
+	require.Equal(t, 1, after.Size())
+	before, after = NewAutoOrderMultiset(1, 2, 2, 3).SplitAfter(2)
+	require.Equal(t, []int{1, 2, 2}, slices.Collect(before.Values()))
+	require.Equal(t, []int{3}, slices.Collect(after.Values()))
+}
+
+func TestMultisetMatchesTreap(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(13, 14))
+	m := NewAutoOrderMultiset[int]()
+	tr := NewAutoOrderTreap[int]()
+
+	for range 3000 {
+		v := rnd.IntN(30)
+		switch rnd.IntN(6) {
+		case 0, 1:
+			require.Equal(t, tr.InsertRight(v), m.InsertRight(v))
+		case 2:
+			require.Equal(t, tr.InsertLeft(v), m.InsertLeft(v))
+		case 3:
+			n := rnd.IntN(4)
+			require.Equal(t, tr.EraseLeftmost(v, n), m.EraseLeftmost(v, n))
+		case 4:
+			hi := v + rnd.IntN(5)
+			incStart, incEnd := rnd.IntN(2) == 0, rnd.IntN(2) == 0
+			if v == hi {
+				incStart, incEnd = true, true
+			}
+			require.Equal(t, tr.CountRange(v, incStart, hi, incEnd), m.CountRange(v, incStart, hi, incEnd))
+			if rnd.IntN(10) == 0 {
+				require.Equal(t, tr.EraseRange(v, incStart, hi, incEnd), m.EraseRange(v, incStart, hi, incEnd))
+			}
+		case 5:
+			if tr.Size() > 0 {
+				i := rnd.IntN(tr.Size())
+				value, ok := m.At(i)
+				require.True(t, ok)
+				require.Equal(t, tr.At(i).Value(), value)
+			}
+		}
+		require.Equal(t, tr.Count(v), m.Count(v))
+	}
+
+	require.Equal(t, mustValues(tr), slices.Collect(m.Values()))
+	require.Equal(t, tr.Size(), m.Size())
+	requireMultisetInvariants(t, m)
+}
@@ -2,6 +2,10 @@ package gotreap
 
 type leftCondition[T any] func(nodeValue T, nodeIndex int) bool
 
+// prefixCondition is a leftCondition that also receives the aggregate of every value
+// from the start of the tree up to and including the node.
+type prefixCondition[T any] func(prefix T, nodeValue T, nodeIndex int) bool
+
 type Node[T any] struct {
 	value          T
 	heightPriority int
@@ -9,6 +13,31 @@ type Node[T any] struct {
 	right          *Node[T]
 	parent         *Node[T]
 	size           int
+	// ext holds the state of optional tree features, nil for nodes of plain treaps.
+	ext *nodeExt[T]
+}
+
+// nodeExt holds the per-node state of optional tree features. It is only allocated for trees
+// that enable one, so plain treaps pay a single nil pointer per node.
+type nodeExt[T any] struct {
+	traits *nodeTraits[T]
+	// aggregate points at the cached aggregate of the subtree, or is nil when the tree maintains none.
+	aggregate *T
+	// contentHash points at the cached content hash of the subtree, or is nil when the tree maintains none.
+	contentHash *seqHash
+	// pending and reversed are range updates not yet applied to the children, see push.
+	pending  rangeUpdate[T]
+	reversed bool
+}
+
+// nodeTraits holds behaviour shared by every node of one tree.
+type nodeTraits[T any] struct {
+	// lazy marks trees that may carry pending range updates, which navigation must push down first.
+	lazy bool
+	// aggregate, when set, is combined over each subtree and cached in nodeExt.aggregate.
+	aggregate func(a T, b T) T
+	// hash, when set, hashes single values for the subtree content hash cached in nodeExt.contentHash.
+	hash func(value T) uint64
 }
 
 // newNode creates a new treap node containing value with a random heap priority.
@@ -23,6 +52,58 @@ func newNode[T any](value T, heightPriority int) *Node[T] {
 	}
 }
 
+// newExtNode creates a node for a tree with traits. The node, its extension and the storage
+// of the features the tree enables share one allocation.
+func newExtNode[T any](value T, heightPriority int, traits *nodeTraits[T]) *Node[T] {
+	var node *Node[T]
+	var ext *nodeExt[T]
+	switch {
+	case traits.aggregate != nil && traits.hash != nil:
+		n := &struct {
+			node        Node[T]
+			ext         nodeExt[T]
+			aggregate   T
+			contentHash seqHash
+		}{}
+		node, ext = &n.node, &n.ext
+		ext.aggregate, ext.contentHash = &n.aggregate, &n.contentHash
+	case traits.aggregate != nil:
+		n := &struct {
+			node      Node[T]
+			ext       nodeExt[T]
+			aggregate T
+		}{}
+		node, ext = &n.node, &n.ext
+		ext.aggregate = &n.aggregate
+	case traits.hash != nil:
+		n := &struct {
+			node        Node[T]
+			ext         nodeExt[T]
+			contentHash seqHash
+		}{}
+		node, ext = &n.node, &n.ext
+		ext.contentHash = &n.contentHash
+	default:
+		n := &struct {
+			node Node[T]
+			ext  nodeExt[T]
+		}{}
+		node, ext = &n.node, &n.ext
+	}
+
+	*node = Node[T]{value: value, heightPriority: heightPriority, size: 1, ext: ext}
+	ext.traits = traits
+	return node
+}
+
+// traits returns the traits of the tree t belongs to, or nil for plain treaps.
+func (t *Node[T]) traits() *nodeTraits[T] {
+	if t.ext == nil {
+		return nil
+	}
+	return t.ext.traits
+}
+
 // safeSize returns the subtree size stored in t, treating a nil node as zero.
 func (t *Node[T]) safeSize() int {
 	if t == nil {
@@ -36,6 +117,138 @@ func (t *Node[T]) recalcSize() {
 	t.size = t.left.safeSize() + 1 + t.right.safeSize()
 }
 
+// recalc recomputes every cached subtree property of t from its children:
+// the size and, when the tree maintains them, the aggregate and the content hash.
+// Plain treaps skip everything but the size.
+func (t *Node[T]) recalc() {
+	t.recalcSize()
+	if t.ext != nil {
+		t.recalcExt()
+	}
+}
+
+// recalcExt recomputes the cached subtree properties of the features enabled for t's tree.
+func (t *Node[T]) recalcExt() {
+	if t.ext.traits.aggregate != nil {
+		t.recalcAggregate()
+	}
+	if t.ext.traits.hash != nil {
+		t.recalcHash()
+	}
+}
+
+// subtreeAggregate returns the cached aggregate of the subtree rooted at t.
+// Only valid for trees that maintain an aggregate.
+func (t *Node[T]) subtreeAggregate() T {
+	return *t.ext.aggregate
+}
+
+// recalcAggregate recomputes the cached aggregate by combining the children's aggregates with t.value in order.
+func (t *Node[T]) recalcAggregate() {
+	combine := t.ext.traits.aggregate
+	aggregate := t.value
+	if t.left != nil {
+		aggregate = combine(t.left.subtreeAggregate(), aggregate)
+	}
+	if t.right != nil {
+		aggregate = combine(aggregate, t.right.subtreeAggregate())
+	}
+	*t.ext.aggregate = aggregate
+}
+
+// aggregateIndexRange combines the values whose indices fall in [from, to)
+// within the subtree rooted at t, whose first element has index indexOffset.
+func (t *Node[T]) aggregateIndexRange(from, to int, indexOffset int) (result T, ok bool) {
+	if t == nil || to <= indexOffset || indexOffset+t.size <= from {
+		return result, false
+	}
+	if from <= indexOffset && indexOffset+t.size <= to {
+		return t.subtreeAggregate(), true
+	}
+
+	combine := t.ext.traits.aggregate
+	centralIndexOffset := indexOffset + t.left.safeSize()
+
+	result, ok = t.left.aggregateIndexRange(from, to, indexOffset)
+	if from <= centralIndexOffset && centralIndexOffset < to {
+		if ok {
+			result = combine(result, t.value)
+		} else {
+			result, ok = t.value, true
+		}
+	}
+	if right, rightOk := t.right.aggregateIndexRange(from, to, centralIndexOffset+1); rightOk {
+		if ok {
+			result = combine(result, right)
+		} else {
+			result, ok = right, true
+		}
+	}
+	return result, ok
+}
+
+// isLazy reports whether t belongs to a tree that may carry pending range updates.
+func (t *Node[T]) isLazy() bool {
+	return t.ext != nil && t.ext.traits.lazy
+}
+
+// applyReverse reverses the subtree rooted at t.
+// Children are swapped immediately while their own subtrees are reversed lazily.
+func (t *Node[T]) applyReverse() {
+	if t == nil {
+		return
+	}
+	t.left, t.right = t.right, t.left
+	t.ext.reversed = !t.ext.reversed
+}
+
+// applyUpdate applies update to every value in the subtree rooted at t.
+// The value of t changes immediately while its children receive the update lazily.
+func (t *Node[T]) applyUpdate(update rangeUpdate[T]) {
+	if t == nil {
+		return
+	}
+	t.value = update.apply(t.value)
+	if t.ext.pending == nil {
+		t.ext.pending = update
+	} else {
+		t.ext.pending = t.ext.pending.then(update)
+	}
+}
+
+// push propagates the pending range updates of t to its children.
+// Kept small enough to inline, so it is a single check for nodes of plain treaps.
+func (t *Node[T]) push() {
+	if t != nil && t.ext != nil {
+		t.pushExt()
+	}
+}
+
+// pushExt propagates the pending range updates of a node with an extension to its children.
+// Only lazy trees ever set them.
+func (t *Node[T]) pushExt() {
+	ext := t.ext
+	if ext.reversed {
+		t.left.applyReverse()
+		t.right.applyReverse()
+		ext.reversed = false
+	}
+	if ext.pending != nil {
+		t.left.applyUpdate(ext.pending)
+		t.right.applyUpdate(ext.pending)
+		ext.pending = nil
+	}
+}
+
+// pushPath pushes every ancestor of t from the root down and then t itself,
+// so the value and children of t become current.
+func (t *Node[T]) pushPath() {
+	if t.parent != nil {
+		t.parent.pushPath()
+	}
+	t.push()
+}
+
 // safeSetParent assigns parent to t when t is non-nil.
 func (t *Node[T]) safeSetParent(parent *Node[T]) {
 	if t == nil {
@@ -54,15 +267,17 @@ func merge[T any](left *Node[T], right *Node[T]) *Node[T] {
 	}
 
 	if left.heightPriority >= right.heightPriority {
+		left.push()
 		left.right = merge(left.right, right)
 		left.right.safeSetParent(left)
-		left.recalcSize()
+		left.recalc()
 		return left
 	}
 
+	right.push()
 	right.left = merge(left, right.left)
 	right.left.safeSetParent(right)
-	right.recalcSize()
+	right.recalc()
 	return right
 }
 
@@ -72,19 +287,20 @@ func (t *Node[T]) split(leftCond leftCondition[T], indexOffset int) (left, right
 		return nil, nil
 	}
 
+	t.push()
 	centralIndexOffset := indexOffset + t.left.safeSize()
 	if leftCond(t.value, centralIndexOffset) {
 		t.right, right = t.right.split(leftCond, centralIndexOffset+1)
 		t.right.safeSetParent(t)
 		right.safeSetParent(nil)
-		t.recalcSize()
+		t.recalc()
 		return t, right
 	}
 
 	left, t.left = t.left.split(leftCond, indexOffset)
 	left.safeSetParent(nil)
 	t.left.safeSetParent(t)
-	t.recalcSize()
+	t.recalc()
 	return left, t
 }
 
@@ -93,11 +309,16 @@ func (t *Node[T]) Prev() *Node[T] {
 	if t == nil {
 		return nil
 	}
+	if t.isLazy() {
+		t.pushPath()
+	}
 
 	if t.left != nil {
 		cur := t.left
+		cur.push()
 		for cur.right != nil {
 			cur = cur.right
+			cur.push()
 		}
 		return cur
 	}
@@ -115,11 +336,16 @@ func (t *Node[T]) Next() *Node[T] {
 	if t == nil {
 		return nil
 	}
+	if t.isLazy() {
+		t.pushPath()
+	}
 
 	if t.right != nil {
 		cur := t.right
+		cur.push()
 		for cur.left != nil {
 			cur = cur.left
+			cur.push()
 		}
 		return cur
 	}
@@ -142,8 +368,10 @@ func (t *Node[T]) Leftmost() *Node[T] {
 	for cur.parent != nil {
 		cur = cur.parent
 	}
+	cur.push()
 	for cur.left != nil {
 		cur = cur.left
+		cur.push()
 	}
 	return cur
 }
@@ -158,8 +386,10 @@ func (t *Node[T]) Rightmost() *Node[T] {
 	for cur.parent != nil {
 		cur = cur.parent
 	}
+	cur.push()
 	for cur.right != nil {
 		cur = cur.right
+		cur.push()
 	}
 	return cur
 }
@@ -170,6 +400,9 @@ func (t *Node[T]) Index() int {
 	if t == nil {
 		return -1
 	}
+	if t.isLazy() {
+		t.pushPath()
+	}
 
 	indexOffset := t.left.safeSize()
 	for cur := t; cur.parent != nil; cur = cur.parent {
@@ -188,6 +421,9 @@ func (t *Node[T]) Valid() bool {
 // Value returns the stored node value or the zero value if t is nil.
 func (t *Node[T]) Value() (result T) {
 	if t != nil {
+		if t.isLazy() {
+			t.pushPath()
+		}
 		result = t.value
 	}
 	return result
@@ -199,6 +435,7 @@ func (t *Node[T]) lookupRightmostMatch(leftCond leftCondition[T], indexOffset in
 		return nil, 0
 	}
 
+	t.push()
 	centralIndexOffset := indexOffset + t.left.safeSize()
 	if leftCond(t.value, centralIndexOffset) {
 		res, idx := t.right.lookupRightmostMatch(leftCond, centralIndexOffset+1)
@@ -217,6 +454,7 @@ func (t *Node[T]) lookupLeftmostUnmatch(leftCond leftCondition[T], indexOffset i
 		return nil, 0
 	}
 
+	t.push()
 	centralIndexOffset := indexOffset + t.left.safeSize()
 	if leftCond(t.value, centralIndexOffset) {
 		return t.right.lookupLeftmostUnmatch(leftCond, centralIndexOffset+1)
@@ -229,26 +467,66 @@ func (t *Node[T]) lookupLeftmostUnmatch(leftCond leftCondition[T], indexOffset i
 	return t, centralIndexOffset
 }
 
+// lookupLeftmostUnmatchPrefix works like lookupLeftmostUnmatch but passes leftCond the prefix aggregate
+// of each visited node. acc is the aggregate of every value left of t's subtree, valid when hasAcc is set.
+func (t *Node[T]) lookupLeftmostUnmatchPrefix(leftCond prefixCondition[T], acc T, hasAcc bool, indexOffset int) (node *Node[T], index int) {
+	if t == nil {
+		return nil, 0
+	}
+
+	t.push()
+	combine := t.ext.traits.aggregate
+	prefix := t.value
+	if t.left != nil {
+		prefix = combine(t.left.subtreeAggregate(), prefix)
+	}
+	if hasAcc {
+		prefix = combine(acc, prefix)
+	}
+
+	centralIndexOffset := indexOffset + t.left.safeSize()
+	if leftCond(prefix, t.value, centralIndexOffset) {
+		return t.right.lookupLeftmostUnmatchPrefix(leftCond, prefix, true, centralIndexOffset+1)
+	}
+
+	res, idx := t.left.lookupLeftmostUnmatchPrefix(leftCond, acc, hasAcc, indexOffset)
+	if res != nil {
+		return res, idx
+	}
+	return t, centralIndexOffset
+}
+
 // JumpRight will return element that is n positions to the right,
 // or -n positions to the left n is negative.
 // If there's no such element, nil will be returned.
 func (t *Node[T]) JumpRight(n int) *Node[T] {
+	if t != nil && t.isLazy() {
+		t.pushPath()
+	}
+	return t.jumpRight(n)
+}
+
+// jumpRight implements JumpRight assuming every ancestor of t has already been pushed.
+func (t *Node[T]) jumpRight(n int) *Node[T] {
 	if t == nil || n == 0 {
 		return t
 	}
+	t.push()
 	if n < 0 && t.left.safeSize() >= -n {
-		return t.left.JumpRight(n + 1 + t.left.right.safeSize())
+		t.left.push()
+		return t.left.jumpRight(n + 1 + t.left.right.safeSize())
 	}
 	if n > 0 && t.right.safeSize() >= n {
-		return t.right.JumpRight(n - 1 - t.right.left.safeSize())
+		t.right.push()
+		return t.right.jumpRight(n - 1 - t.right.left.safeSize())
 	}
 	if t.parent == nil {
 		return nil
 	}
 	if t.parent.left == t {
-		return t.parent.JumpRight(n - 1 - t.right.safeSize())
+		return t.parent.jumpRight(n - 1 - t.right.safeSize())
 	}
-	return t.parent.JumpRight(n + 1 + t.left.safeSize())
+	return t.parent.jumpRight(n + 1 + t.left.safeSize())
 }
 
 // JumpLeft will return element that is n positions to the left,
@@ -257,3 +535,22 @@ func (t *Node[T]) JumpRight(n int) *Node[T] {
 func (t *Node[T]) JumpLeft(n int) *Node[T] {
 	return t.JumpRight(-n)
 }
+
+// clone returns a deep copy of the subtree rooted at t with a detached root.
+func (t *Node[T]) clone() *Node[T] {
+	if t == nil {
+		return nil
+	}
+
+	t.push()
+	c := newNode(t.value, t.heightPriority)
+	if t.ext != nil {
+		c = newExtNode(t.value, t.heightPriority, t.ext.traits)
+	}
+	c.left = t.left.clone()
+	c.right = t.right.clone()
+	c.left.safeSetParent(c)
+	c.right.safeSetParent(c)
+	c.recalc()
+	return c
+}
@@ -0,0 +1,156 @@
+package gotreap
+
+// requireOwned panics unless node is part of t.
+func (t *Treap[T]) requireOwned(node *Node[T]) {
+	if node == nil {
+		panic("node must not be nil")
+	}
+	root := node
+	for root.parent != nil {
+		root = root.parent
+	}
+	if root != t.root {
+		panic("node does not belong to this treap")
+	}
+}
+
+// recalcPath refreshes the cached subtree properties of node and all its ancestors.
+func recalcPath[T any](node *Node[T]) {
+	for cur := node; cur != nil; cur = cur.parent {
+		cur.recalc()
+	}
+}
+
+// detach unlinks node from t, putting the merge of its children in its place,
+// and leaves node as a standalone single-element tree.
+// In a canonical treap the equal elements following node move down in rank and are re-ranked.
+func (t *Treap[T]) detach(node *Node[T]) {
+	next := node.Next()
+	rerank := t.Canonical() && next != nil && !t.lessFn(node.value, next.value)
+
+	parent := node.parent
+	replacement := merge(node.left, node.right)
+	replacement.safeSetParent(parent)
+
+	switch {
+	case parent == nil:
+		t.root = replacement
+	case parent.left == node:
+		parent.left = replacement
+	default:
+		parent.right = replacement
+	}
+	recalcPath(parent)
+
+	node.left, node.right, node.parent = nil, nil, nil
+	node.recalc()
+
+	if rerank {
+		t.root = t.rerank(t.root, next.value)
+	}
+}
+
+// insertNodeNear links a standalone node into t at index like insertNodeAt. In a canonical treap
+// inserting next to equal elements shifts their ranks, so the run is re-ranked.
+func (t *Treap[T]) insertNodeNear(node *Node[T], index int) {
+	t.insertNodeAt(node, index)
+	if !t.Canonical() {
+		return
+	}
+	prev, next := node.Prev(), node.Next()
+	if (prev != nil && !t.lessFn(prev.value, node.value)) || (next != nil && !t.lessFn(node.value, next.value)) {
+		t.root = t.rerank(t.root, node.value)
+	}
+}
+
+// insertNodeAt links a standalone node into t so that it ends up at index.
+func (t *Treap[T]) insertNodeAt(node *Node[T], index int) {
+	left, right := t.root.split(condCutN[T](index), 0)
+	t.root = merge(merge(left, node), right)
+}
+
+// fitsBetween reports whether value can sit between prev and next without breaking the order,
+// or, unless the treap allows duplicates, without equaling either of them.
+// Either neighbour may be nil.
+func (t *Treap[T]) fitsBetween(prev *Node[T], value T, next *Node[T]) bool {
+	if t.duplicates != DuplicatesAllow {
+		return (prev == nil || t.lessFn(prev.value, value)) && (next == nil || t.lessFn(value, next.value))
+	}
+	return (prev == nil || !t.lessFn(value, prev.value)) && (next == nil || !t.lessFn(next.value, value))
+}
+
+// EraseNode removes node from the treap in O(log n) without searching by value,
+// so exactly this element is erased even among duplicates. The node must not be used afterwards.
+// Panics if node is nil or does not belong to the treap.
+func (t *Treap[T]) EraseNode(node *Node[T]) {
+	t.requireOwned(node)
+	defer t.debugCheck()
+
+	t.detach(node)
+}
+
+// ReplaceValue stores value in node and returns the node's resulting index.
+// If value keeps its position in the order it is updated in place, otherwise (and always for
+// canonical treaps) the node is moved after any elements equal to value, like InsertRight.
+// Either way node stays valid.
+// Panics if node is nil, does not belong to the treap, or, unless the treap allows duplicates,
+// value equals an element other than node.
+func (t *Treap[T]) ReplaceValue(node *Node[T], value T) (index int) {
+	t.requireOwned(node)
+	defer t.debugCheck()
+
+	// A canonical treap derives the priority from the value, so the node always has to move.
+	if !t.Canonical() && t.fitsBetween(node.Prev(), value, node.Next()) {
+		node.value = value
+		recalcPath(node)
+		return node.Index()
+	}
+	if t.duplicates != DuplicatesAllow && (t.lessFn(node.value, value) || t.lessFn(value, node.value)) && t.Count(value) > 0 {
+		panic("value equals another element of a treap without duplicates")
+	}
+
+	t.detach(node)
+	index = t.prefixLen(t.condLeq(value))
+	rank := 0
+	if t.Canonical() {
+		rank = index - t.prefixLen(t.condLess(value))
+	}
+	node.value = value
+	node.heightPriority = t.priority(value, rank)
+	node.recalc()
+
+	t.insertNodeAt(node, index)
+	return index
+}
+
+// InsertAfterNode inserts value immediately after node and returns the new node.
+// Useful to control the position among duplicates.
+// Panics if node is nil, does not belong to the treap, or value does not fit between node and its successor,
+// which unless the treap allows duplicates includes equaling either of them.
+func (t *Treap[T]) InsertAfterNode(node *Node[T], value T) *Node[T] {
+	t.requireOwned(node)
+	if !t.fitsBetween(node, value, node.Next()) {
+		panic("value does not fit the order after node")
+	}
+	defer t.debugCheck()
+
+	inserted := t.newNode(value, 0)
+	t.insertNodeNear(inserted, node.Index()+1)
+	return inserted
+}
+
+// InsertBeforeNode inserts value immediately before node and returns the new node.
+// Useful to control the position among duplicates.
+// Panics if node is nil, does not belong to the treap, or value does not fit between node and its predecessor,
+// which unless the treap allows duplicates includes equaling either of them.
+func (t *Treap[T]) InsertBeforeNode(node *Node[T], value T) *Node[T] {
+	t.requireOwned(node)
+	if !t.fitsBetween(node.Prev(), value, node) {
+		panic("value does not fit the order before node")
+	}
+	defer t.debugCheck()
+
+	inserted := t.newNode(value, 0)
+	t.insertNodeNear(inserted, node.Index())
+	return inserted
+}
@@ -0,0 +1,125 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tagged struct {
+	key int
+	tag string
+}
+
+func taggedLess(a, b tagged) bool { return a.key < b.key }
+
+func TestEraseNodeRemovesExactDuplicate(t *testing.T) {
+	tr := NewTreapWithRand(taggedLess, staticRand(),
+		tagged{1, "a"}, tagged{2, "b"}, tagged{2, "c"}, tagged{2, "d"}, tagged{3, "e"},
+	)
+
+	tr.EraseNode(tr.At(2))
+	requireTreapValues(t, tr, tagged{1, "a"}, tagged{2, "b"}, tagged{2, "d"}, tagged{3, "e"})
+	require.NoError(t, tr.Validate())
+
+	tr.EraseNode(tr.Root())
+	require.Equal(t, 3, tr.Size())
+	require.NoError(t, tr.Validate())
+
+	other := NewTreapWithRand(taggedLess, staticRand(), tagged{1, "x"})
+	require.Panics(t, func() { tr.EraseNode(other.Root()) })
+	require.Panics(t, func() { tr.EraseNode(nil) })
+}
+
+func TestEraseNodeKeepsAggregates(t *testing.T) {
+	tr := NewTreapWithOptions(func(a, b int) bool { return a < b }, Options[int]{RandFn: staticRand(), Aggregate: sumInts}, 1, 2, 3, 4, 5)
+
+	tr.EraseNode(tr.At(3))
+	total, _ := tr.Aggregate()
+	require.Equal(t, 11, total)
+
+	tr.ReplaceValue(tr.At(0), 2)
+	total, _ = tr.Aggregate()
+	require.Equal(t, 12, total)
+
+	tr.ReplaceValue(tr.At(0), 10)
+	total, _ = tr.Aggregate()
+	require.Equal(t, 20, total)
+	requireTreapValues(t, tr, 2, 3, 5, 10)
+}
+
+func TestReplaceValue(t *testing.T) {
+	tr := NewTreapWithRand(taggedLess, staticRand(), tagged{1, "a"}, tagged{5, "b"}, tagged{9, "c"})
+
+	// In place: the order is preserved and the handle keeps pointing at the element.
+	node := tr.At(1)
+	require.Equal(t, 1, tr.ReplaceValue(node, tagged{6, "b2"}))
+	require.Equal(t, tagged{6, "b2"}, node.Value())
+
+	// Equal to the successor still fits in place.
+	require.Equal(t, 1, tr.ReplaceValue(node, tagged{9, "b3"}))
+	requireTreapValues(t, tr, tagged{1, "a"}, tagged{9, "b3"}, tagged{9, "c"})
+
+	// Repositioned; the handle follows the element.
+	require.Equal(t, 0, tr.ReplaceValue(node, tagged{0, "b4"}))
+	require.Equal(t, 0, node.Index())
+	requireTreapValues(t, tr, tagged{0, "b4"}, tagged{1, "a"}, tagged{9, "c"})
+
+	// Moving lands after equal elements, like InsertRight.
+	require.Equal(t, 2, tr.ReplaceValue(node, tagged{9, "b5"}))
+	requireTreapValues(t, tr, tagged{1, "a"}, tagged{9, "c"}, tagged{9, "b5"})
+	require.NoError(t, tr.Validate())
+}
+
+func TestInsertAroundNode(t *testing.T) {
+	tr := NewTreapWithRand(taggedLess, staticRand(), tagged{1, "a"}, tagged{2, "b"}, tagged{2, "c"}, tagged{4, "d"})
+
+	anchor := tr.At(1)
+	after := tr.InsertAfterNode(anchor, tagged{2, "after-b"})
+	require.Equal(t, 2, after.Index())
+	before := tr.InsertBeforeNode(anchor, tagged{2, "before-b"})
+	require.Equal(t, 1, before.Index())
+	tr.InsertAfterNode(tr.At(-1), tagged{7, "last"})
+	tr.InsertBeforeNode(tr.At(0), tagged{0, "first"})
+
+	requireTreapValues(t, tr,
+		tagged{0, "first"}, tagged{1, "a"}, tagged{2, "before-b"}, tagged{2, "b"},
+		tagged{2, "after-b"}, tagged{2, "c"}, tagged{4, "d"}, tagged{7, "last"},
+	)
+	require.NoError(t, tr.Validate())
+
+	require.Panics(t, func() { tr.InsertAfterNode(tr.At(1), tagged{3, "x"}) })
+	require.Panics(t, func() { tr.InsertBeforeNode(tr.At(1), tagged{-1, "x"}) })
+}
+
+func TestNodeHandleOperationsRandomized(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(5, 8))
+	tr := NewAutoOrderTreapWithRand[int](staticRand())
+	var expected []int
+
+	for range 2000 {
+		switch op := rnd.IntN(4); {
+		case op == 0 && len(expected) > 0:
+			idx := rnd.IntN(len(expected))
+			tr.EraseNode(tr.At(idx))
+			expected = slices.Delete(expected, idx, idx+1)
+		case op == 1 && len(expected) > 0:
+			idx := rnd.IntN(len(expected))
+			value := rnd.IntN(100)
+			tr.ReplaceValue(tr.At(idx), value)
+			expected = slices.Delete(expected, idx, idx+1)
+			pos, _ := slices.BinarySearch(expected, value+1)
+			expected = slices.Insert(expected, pos, value)
+		default:
+			value := rnd.IntN(100)
+			tr.InsertRight(value)
+			pos, _ := slices.BinarySearch(expected, value+1)
+			expected = slices.Insert(expected, pos, value)
+		}
+	}
+
+	require.Equal(t, expected, mustValues(tr))
+	require.NoError(t, tr.Validate())
+}
@@ -395,3 +395,37 @@ func TestSplitDetachesParentsAcrossPartitions(t *testing.T) {
 	verifyParents(left)
 	verifyParents(right)
 }
+
+// mustLazyNode works like mustNode but creates a node of a tree carrying range updates.
+func mustLazyNode[T any](value T, priority int, left *Node[T], right *Node[T]) *Node[T] {
+	n := newExtNode(value, priority, &nodeTraits[T]{lazy: true})
+	n.left, n.right = left, right
+	left.safeSetParent(n)
+	right.safeSetParent(n)
+	n.recalcSize()
+	return n
+}
+
+func TestPushPropagatesPendingUpdates(t *testing.T) {
+	root := mustLazyNode(2, 200,
+		mustLazyNode(1, 100, nil, nil),
+		mustLazyNode(3, 150, nil, nil),
+	)
+
+	root.applyReverse()
+	root.applyUpdate(addUpdate[int]{delta: 10})
+	require.Equal(t, 12, root.value)
+	require.Equal(t, 3, root.left.value)
+	require.NotNil(t, root.ext.pending)
+	require.True(t, root.ext.reversed)
+
+	root.push()
+	require.Nil(t, root.ext.pending)
+	require.False(t, root.ext.reversed)
+	requireInOrder(t, root, 13, 12, 11)
+
+	root.applyUpdate(assignUpdate[int]{value: 5})
+	root.applyUpdate(addUpdate[int]{delta: 1})
+	root.push()
+	requireInOrder(t, root, 6, 6, 6)
+}
@@ -0,0 +1,401 @@
+package gotreap
+
+import (
+	"cmp"
+	"iter"
+	"math/rand/v2"
+	"sort"
+)
+
+type persistentNode[T any] struct {
+	value          T
+	heightPriority int
+	left           *persistentNode[T]
+	right          *persistentNode[T]
+	size           int
+}
+
+// newPersistentNode creates an immutable node with the provided children and a computed size.
+func newPersistentNode[T any](value T, heightPriority int, left, right *persistentNode[T]) *persistentNode[T] {
+	return &persistentNode[T]{
+		value:          value,
+		heightPriority: heightPriority,
+		left:           left,
+		right:          right,
+		size:           left.safeSize() + 1 + right.safeSize(),
+	}
+}
+
+// safeSize returns the subtree size stored in t, treating a nil node as zero.
+func (t *persistentNode[T]) safeSize() int {
+	if t == nil {
+		return 0
+	}
+	return t.size
+}
+
+// withChildren returns a copy of t that points at the provided children.
+func (t *persistentNode[T]) withChildren(left, right *persistentNode[T]) *persistentNode[T] {
+	return newPersistentNode(t.value, t.heightPriority, left, right)
+}
+
+// mergePersistent combines two subtrees like merge, copying every node it has to modify.
+func mergePersistent[T any](left *persistentNode[T], right *persistentNode[T]) *persistentNode[T] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	if left.heightPriority >= right.heightPriority {
+		return left.withChildren(left.left, mergePersistent(left.right, right))
+	}
+	return right.withChildren(mergePersistent(left, right.left), right.right)
+}
+
+// split partitions the subtree like Node.split, copying the nodes along the split path.
+func (t *persistentNode[T]) split(leftCond leftCondition[T], indexOffset int) (left, right *persistentNode[T]) {
+	if t == nil {
+		return nil, nil
+	}
+
+	centralIndexOffset := indexOffset + t.left.safeSize()
+	if leftCond(t.value, centralIndexOffset) {
+		middle, right := t.right.split(leftCond, centralIndexOffset+1)
+		return t.withChildren(t.left, middle), right
+	}
+
+	left, middle := t.left.split(leftCond, indexOffset)
+	return left, t.withChildren(middle, t.right)
+}
+
+// lookupRightmostMatch finds the rightmost node satisfying leftCond together with its index.
+func (t *persistentNode[T]) lookupRightmostMatch(leftCond leftCondition[T], indexOffset int) (node *persistentNode[T], index int) {
+	for cur := t; cur != nil; {
+		centralIndexOffset := indexOffset + cur.left.safeSize()
+		if leftCond(cur.value, centralIndexOffset) {
+			node, index = cur, centralIndexOffset
+			cur, indexOffset = cur.right, centralIndexOffset+1
+		} else {
+			cur = cur.left
+		}
+	}
+	return node, index
+}
+
+// lookupLeftmostUnmatch finds the leftmost node that fails leftCond and returns it with its index.
+func (t *persistentNode[T]) lookupLeftmostUnmatch(leftCond leftCondition[T], indexOffset int) (node *persistentNode[T], index int) {
+	for cur := t; cur != nil; {
+		centralIndexOffset := indexOffset + cur.left.safeSize()
+		if leftCond(cur.value, centralIndexOffset) {
+			cur, indexOffset = cur.right, centralIndexOffset+1
+		} else {
+			node, index = cur, centralIndexOffset
+			cur = cur.left
+		}
+	}
+	return node, index
+}
+
+// PersistentTreap is an immutable treap. Every modifying operation returns a new version
+// that shares all untouched nodes with the receiver, so it costs O(log n) extra memory and
+// previous versions stay valid forever. Versions are safe for concurrent readers.
+type PersistentTreap[T any] struct {
+	lessFn func(a T, b T) bool
+	randFn func() int
+	root   *persistentNode[T]
+}
+
+// NewAutoOrderPersistentTreap builds a persistent treap using the natural ordering for type T.
+func NewAutoOrderPersistentTreap[T cmp.Ordered](values ...T) *PersistentTreap[T] {
+	return NewPersistentTreap(cmp.Less[T], values...)
+}
+
+// NewAutoOrderPersistentTreapWithRand builds a persistent treap using the natural ordering for type T and a custom random function.
+func NewAutoOrderPersistentTreapWithRand[T cmp.Ordered](randFn func() int, values ...T) *PersistentTreap[T] {
+	return NewPersistentTreapWithRand(cmp.Less[T], randFn, values...)
+}
+
+// NewPersistentTreap constructs a persistent treap using lessFn for ordering and optionally inserts values.
+func NewPersistentTreap[T any](lessFn func(a T, b T) bool, values ...T) *PersistentTreap[T] {
+	return NewPersistentTreapWithRand(lessFn, rand.Int, values...)
+}
+
+// NewPersistentTreapWithRand constructs a persistent treap using lessFn for ordering, randFn for tree balancing, and optionally inserts values.
+// randFn must be safe for concurrent use if versions are modified from several goroutines.
+func NewPersistentTreapWithRand[T any](lessFn func(a T, b T) bool, randFn func() int, values ...T) *PersistentTreap[T] {
+	if lessFn == nil {
+		panic("lessFn must not be nil")
+	}
+	if randFn == nil {
+		panic("randFn must not be nil")
+	}
+
+	t := &PersistentTreap[T]{
+		lessFn: lessFn,
+		randFn: randFn,
+		root:   nil,
+	}
+
+	sort.Slice(values, func(i, j int) bool {
+		return lessFn(values[i], values[j])
+	})
+
+	for _, val := range values {
+		t.root = mergePersistent(t.root, newPersistentNode(val, randFn(), nil, nil))
+	}
+
+	return t
+}
+
+// withRoot creates a new version sharing t's configuration with the provided root.
+func (t *PersistentTreap[T]) withRoot(root *persistentNode[T]) *PersistentTreap[T] {
+	return &PersistentTreap[T]{
+		lessFn: t.lessFn,
+		randFn: t.randFn,
+		root:   root,
+	}
+}
+
+// condLess returns a predicate that is true for nodes whose value is less than value.
+func (t *PersistentTreap[T]) condLess(value T) leftCondition[T] {
+	return func(nodeValue T, nodeIndex int) bool {
+		return t.lessFn(nodeValue, value)
+	}
+}
+
+// condLeq returns a predicate that is true for nodes whose value is less than or equal to value.
+func (t *PersistentTreap[T]) condLeq(value T) leftCondition[T] {
+	return func(nodeValue T, nodeIndex int) bool {
+		return !t.lessFn(value, nodeValue)
+	}
+}
+
+// InsertLeft returns a new version with value inserted before any equal elements, and its index.
+func (t *PersistentTreap[T]) InsertLeft(value T) (result *PersistentTreap[T], index int) {
+	less, greaterOrEqual := t.root.split(t.condLess(value), 0)
+
+	node := newPersistentNode(value, t.randFn(), nil, nil)
+	return t.withRoot(mergePersistent(less, mergePersistent(node, greaterOrEqual))), less.safeSize()
+}
+
+// InsertRight returns a new version with value inserted after any equal elements, and its index.
+func (t *PersistentTreap[T]) InsertRight(value T) (result *PersistentTreap[T], index int) {
+	lessOrEqual, greater := t.root.split(t.condLeq(value), 0)
+
+	node := newPersistentNode(value, t.randFn(), nil, nil)
+	return t.withRoot(mergePersistent(mergePersistent(lessOrEqual, node), greater)), lessOrEqual.safeSize()
+}
+
+// EraseAll returns a new version without any occurrence of value, and how many were deleted.
+func (t *PersistentTreap[T]) EraseAll(value T) (result *PersistentTreap[T], erasedCount int) {
+	return t.EraseRange(value, true, value, true)
+}
+
+// EraseRange returns a new version without the values between startValue and endValue, and how many were erased.
+// Each bound is removed only when its corresponding inclusive flag is true.
+// Panics if endValue < startValue, or if startValue == endValue with non-inclusive bounds.
+func (t *PersistentTreap[T]) EraseRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) (result *PersistentTreap[T], erasedCount int) {
+	if t.lessFn(endValue, startValue) {
+		panic("provided endValue must not be lower than startValue")
+	}
+	if !t.lessFn(startValue, endValue) && (!inclusiveStart || !inclusiveEnd) {
+		panic("when startValue == endValue, both start and end must be inclusive")
+	}
+
+	var leftRemainder, toErase, rightRemainder *persistentNode[T]
+
+	if inclusiveStart {
+		leftRemainder, rightRemainder = t.root.split(t.condLess(startValue), 0)
+	} else {
+		leftRemainder, rightRemainder = t.root.split(t.condLeq(startValue), 0)
+	}
+
+	if inclusiveEnd {
+		toErase, rightRemainder = rightRemainder.split(t.condLeq(endValue), 0)
+	} else {
+		toErase, rightRemainder = rightRemainder.split(t.condLess(endValue), 0)
+	}
+
+	if toErase == nil {
+		return t, 0
+	}
+	return t.withRoot(mergePersistent(leftRemainder, rightRemainder)), toErase.safeSize()
+}
+
+// EraseAt returns a new version without up to count elements starting at index, and how many were erased.
+// Supports negative indexing where -1 refers to the last element.
+// Panics if count is negative.
+func (t *PersistentTreap[T]) EraseAt(index int, count int) (result *PersistentTreap[T], erasedCount int) {
+	if count < 0 {
+		panic("count must not be negative")
+	}
+
+	sz := t.root.safeSize()
+	if index < 0 {
+		index = sz + index
+	}
+	if index < 0 || index >= sz || count == 0 {
+		return t, 0
+	}
+
+	leftRemainder, rightRemainder := t.root.split(condCutN[T](index), 0)
+	toErase, rightRemainder := rightRemainder.split(condCutN[T](count), 0)
+
+	return t.withRoot(mergePersistent(leftRemainder, rightRemainder)), toErase.safeSize()
+}
+
+// FindLowerBound returns the first value not less than value along with its index.
+// The ok flag is false if there is no such value.
+func (t *PersistentTreap[T]) FindLowerBound(value T) (result T, index int, ok bool) {
+	node, index := t.root.lookupLeftmostUnmatch(t.condLess(value), 0)
+	if node == nil {
+		return result, 0, false
+	}
+	return node.value, index, true
+}
+
+// FindUpperBound returns the last value not greater than value along with its index.
+// The ok flag is false if there is no such value.
+func (t *PersistentTreap[T]) FindUpperBound(value T) (result T, index int, ok bool) {
+	node, index := t.root.lookupRightmostMatch(t.condLeq(value), 0)
+	if node == nil {
+		return result, 0, false
+	}
+	return node.value, index, true
+}
+
+// At returns the value located at the provided index, reporting false if it is out of range.
+// Supports negative indexing where -1 refers to the last element.
+func (t *PersistentTreap[T]) At(index int) (value T, ok bool) {
+	sz := t.root.safeSize()
+	if sz == 0 || index < -sz || index >= sz {
+		return value, false
+	}
+	if index < 0 {
+		index = sz + index
+	}
+
+	node, _ := t.root.lookupLeftmostUnmatch(condCutN[T](index), 0)
+	return node.value, true
+}
+
+// CountRange returns how many values fall between startValue and endValue.
+// Each bound contributes to the count only when its inclusive flag is true.
+// Panics if endValue < startValue, or if startValue == endValue with non-inclusive bounds.
+func (t *PersistentTreap[T]) CountRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) int {
+	if t.lessFn(endValue, startValue) {
+		panic("provided endValue must not be lower than startValue")
+	}
+	if !t.lessFn(startValue, endValue) && (!inclusiveStart || !inclusiveEnd) {
+		panic("when startValue == endValue, both start and end must be inclusive")
+	}
+
+	var startIdx, endIdx int
+	if inclusiveStart {
+		startIdx = t.countWhere(t.condLess(startValue))
+	} else {
+		startIdx = t.countWhere(t.condLeq(startValue))
+	}
+	if inclusiveEnd {
+		endIdx = t.countWhere(t.condLeq(endValue))
+	} else {
+		endIdx = t.countWhere(t.condLess(endValue))
+	}
+
+	return max(endIdx-startIdx, 0)
+}
+
+// countWhere returns the number of leading elements satisfying leftCond.
+func (t *PersistentTreap[T]) countWhere(leftCond leftCondition[T]) int {
+	node, index := t.root.lookupRightmostMatch(leftCond, 0)
+	if node == nil {
+		return 0
+	}
+	return index + 1
+}
+
+// Count reports the number of occurrences of value.
+func (t *PersistentTreap[T]) Count(value T) int {
+	return t.CountRange(value, true, value, true)
+}
+
+// Size reports the number of elements stored in this version.
+func (t *PersistentTreap[T]) Size() int {
+	return t.root.safeSize()
+}
+
+// Empty reports whether this version contains no elements.
+func (t *PersistentTreap[T]) Empty() bool {
+	return t.root.safeSize() == 0
+}
+
+// SplitBefore returns the values less than value and the rest as two new versions.
+func (t *PersistentTreap[T]) SplitBefore(value T) (left *PersistentTreap[T], right *PersistentTreap[T]) {
+	l, r := t.root.split(t.condLess(value), 0)
+	return t.withRoot(l), t.withRoot(r)
+}
+
+// SplitAfter returns the values less than or equal to value and the rest as two new versions.
+func (t *PersistentTreap[T]) SplitAfter(value T) (left *PersistentTreap[T], right *PersistentTreap[T]) {
+	l, r := t.root.split(t.condLeq(value), 0)
+	return t.withRoot(l), t.withRoot(r)
+}
+
+// Cut returns the first n elements and the remainder as two new versions.
+// If n is negative, cuts from the end like Treap.Cut.
+func (t *PersistentTreap[T]) Cut(n int) (left *PersistentTreap[T], right *PersistentTreap[T]) {
+	if n < 0 {
+		n = max(t.root.safeSize()+n, 0)
+	}
+	l, r := t.root.split(condCutN[T](n), 0)
+	return t.withRoot(l), t.withRoot(r)
+}
+
+// Iterate over values (leftmost to rightmost)
+func (t *PersistentTreap[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var stack []*persistentNode[T]
+		for cur := t.root; cur != nil || len(stack) > 0; {
+			for ; cur != nil; cur = cur.left {
+				stack = append(stack, cur)
+			}
+			cur, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			if !yield(cur.value) {
+				return
+			}
+			cur = cur.right
+		}
+	}
+}
+
+// Iterate over values in reverse order (rightmost to leftmost)
+func (t *PersistentTreap[T]) ValuesBackwards() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var stack []*persistentNode[T]
+		for cur := t.root; cur != nil || len(stack) > 0; {
+			for ; cur != nil; cur = cur.right {
+				stack = append(stack, cur)
+			}
+			cur, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			if !yield(cur.value) {
+				return
+			}
+			cur = cur.left
+		}
+	}
+}
+
+// MergePersistent joins two versions that share the same ordering function into a new version.
+// Every value of left must not be greater than any value of right. Neither input is modified.
+func MergePersistent[T any](left *PersistentTreap[T], right *PersistentTreap[T]) *PersistentTreap[T] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	return left.withRoot(mergePersistent(left.root, right.root))
+}
@@ -0,0 +1,151 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requirePersistentValues[T any](t *testing.T, tr *PersistentTreap[T], expected ...T) {
+	t.Helper()
+	values := slices.Collect(tr.Values())
+	if values == nil {
+		values = []T{}
+	}
+	if expected == nil {
+		expected = []T{}
+	}
+	require.Equal(t, expected, values)
+}
+
+func TestPersistentInsertKeepsOldVersions(t *testing.T) {
+	v0 := NewAutoOrderPersistentTreapWithRand(staticRand(), 5, 1, 3)
+
+	v1, idx := v0.InsertLeft(3)
+	require.Equal(t, 1, idx)
+	v2, idx := v1.InsertRight(3)
+	require.Equal(t, 3, idx)
+
+	requirePersistentValues(t, v0, 1, 3, 5)
+	requirePersistentValues(t, v1, 1, 3, 3, 5)
+	requirePersistentValues(t, v2, 1, 3, 3, 3, 5)
+	require.Equal(t, 3, v2.Count(3))
+	require.Equal(t, 1, v0.Count(3))
+}
+
+func TestPersistentEraseVariants(t *testing.T) {
+	v0 := NewAutoOrderPersistentTreapWithRand(staticRand(), 1, 2, 2, 3, 4, 5)
+
+	v1, erased := v0.EraseAll(2)
+	require.Equal(t, 2, erased)
+	requirePersistentValues(t, v1, 1, 3, 4, 5)
+
+	v2, erased := v0.EraseRange(2, false, 5, false)
+	require.Equal(t, 2, erased)
+	requirePersistentValues(t, v2, 1, 2, 2, 5)
+
+	v3, erased := v0.EraseAt(-2, 5)
+	require.Equal(t, 2, erased)
+	requirePersistentValues(t, v3, 1, 2, 2, 3)
+
+	same, erased := v0.EraseAll(42)
+	require.Zero(t, erased)
+	require.Same(t, v0, same)
+
+	requirePersistentValues(t, v0, 1, 2, 2, 3, 4, 5)
+
+	require.Panics(t, func() { v0.EraseRange(5, true, 4, true) })
+	require.Panics(t, func() { v0.EraseRange(5, true, 5, false) })
+	require.Panics(t, func() { v0.EraseAt(0, -1) })
+}
+
+func TestPersistentLookups(t *testing.T) {
+	v := NewAutoOrderPersistentTreapWithRand(staticRand(), 10, 20, 20, 30)
+
+	value, idx, ok := v.FindLowerBound(15)
+	require.True(t, ok)
+	require.Equal(t, 20, value)
+	require.Equal(t, 1, idx)
+
+	value, idx, ok = v.FindUpperBound(25)
+	require.True(t, ok)
+	require.Equal(t, 20, value)
+	require.Equal(t, 2, idx)
+
+	_, _, ok = v.FindLowerBound(31)
+	require.False(t, ok)
+	_, _, ok = v.FindUpperBound(5)
+	require.False(t, ok)
+
+	value, ok = v.At(-1)
+	require.True(t, ok)
+	require.Equal(t, 30, value)
+	_, ok = v.At(4)
+	require.False(t, ok)
+
+	require.Equal(t, 3, v.CountRange(10, false, 30, true))
+	require.Equal(t, 0, v.CountRange(21, true, 29, true))
+	require.Equal(t, []int{30, 20, 20, 10}, slices.Collect(v.ValuesBackwards()))
+}
+
+func TestPersistentSplitCutMerge(t *testing.T) {
+	v := NewAutoOrderPersistentTreapWithRand(staticRand(), 1, 2, 3, 4, 5)
+
+	left, right := v.SplitBefore(3)
+	requirePersistentValues(t, left, 1, 2)
+	requirePersistentValues(t, right, 3, 4, 5)
+
+	left, right = v.SplitAfter(3)
+	requirePersistentValues(t, left, 1, 2, 3)
+	requirePersistentValues(t, right, 4, 5)
+
+	left, right = v.Cut(-1)
+	requirePersistentValues(t, left, 1, 2, 3, 4)
+	requirePersistentValues(t, right, 5)
+
+	merged := MergePersistent(right, left)
+	requirePersistentValues(t, merged, 5, 1, 2, 3, 4)
+	requirePersistentValues(t, v, 1, 2, 3, 4, 5)
+
+	require.Same(t, v, MergePersistent(v, nil))
+	require.True(t, NewAutoOrderPersistentTreap[int]().Empty())
+}
+
+func TestPersistentVersionsMatchSlices(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(11, 22))
+	versions := []*PersistentTreap[int]{NewAutoOrderPersistentTreapWithRand[int](staticRand())}
+	expected := [][]int{{}}
+
+	for range 500 {
+		base := rnd.IntN(len(versions))
+		value := rnd.IntN(100)
+
+		var next *PersistentTreap[int]
+		arr := slices.Clone(expected[base])
+		if rnd.IntN(3) == 0 {
+			next, _ = versions[base].EraseAll(value)
+			arr = slices.DeleteFunc(arr, func(x int) bool { return x == value })
+		} else {
+			next, _ = versions[base].InsertRight(value)
+			arr = append(arr, value)
+			slices.Sort(arr)
+		}
+
+		versions = append(versions, next)
+		expected = append(expected, arr)
+	}
+
+	var wg sync.WaitGroup
+	for i := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Equal(t, len(expected[i]), versions[i].Size())
+			requirePersistentValues(t, versions[i], expected[i]...)
+		}()
+	}
+	wg.Wait()
+}
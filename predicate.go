@@ -0,0 +1,52 @@
+package gotreap
+
+import "fmt"
+
+// requireMonotone panics in debug builds unless pred holds exactly on a prefix of the treap
+// (or on a suffix when suffix is true). Compiled to a no-op otherwise.
+func (t *Treap[T]) requireMonotone(pred func(value T, index int) bool, suffix bool) {
+	if !debugMode {
+		return
+	}
+	index := 0
+	var prev bool
+	for value := range t.Values() {
+		cur := pred(value, index)
+		if index > 0 && cur != prev && cur != suffix {
+			panic(fmt.Sprintf("predicate is not monotone: changes back to %t at index %d", cur, index))
+		}
+		prev = cur
+		index++
+	}
+}
+
+// SplitWhere splits the treap into the leading elements satisfying pred and the remainder,
+// clearing the receiver. pred receives each value with its index and must hold on a prefix
+// of the treap: once false it must stay false for every following element,
+// e.g. "timestamp older than X" on a treap ordered by timestamp.
+func (t *Treap[T]) SplitWhere(pred func(value T, index int) bool) (left *Treap[T], right *Treap[T]) {
+	t.requireMonotone(pred, false)
+	return t.split(pred)
+}
+
+// CountWhere returns how many leading elements satisfy pred, which must hold on a prefix, see SplitWhere.
+func (t *Treap[T]) CountWhere(pred func(value T, index int) bool) int {
+	t.requireMonotone(pred, false)
+	return t.prefixLen(pred)
+}
+
+// FindLastWhere returns the last node satisfying pred along with its index, or nil if there is none.
+// pred must hold on a prefix, see SplitWhere.
+func (t *Treap[T]) FindLastWhere(pred func(value T, index int) bool) (node *Node[T], index int) {
+	t.requireMonotone(pred, false)
+	return t.root.lookupRightmostMatch(pred, 0)
+}
+
+// FindFirstWhere returns the first node satisfying pred along with its index, or nil if there is none.
+// Like sort.Search, pred must hold on a suffix: once true it must stay true for every following element.
+func (t *Treap[T]) FindFirstWhere(pred func(value T, index int) bool) (node *Node[T], index int) {
+	t.requireMonotone(pred, true)
+	return t.root.lookupLeftmostUnmatch(func(value T, index int) bool {
+		return !pred(value, index)
+	}, 0)
+}
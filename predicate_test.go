@@ -0,0 +1,66 @@
+package gotreap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitWhere(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 1, 4, 4, 9, 12, 20)
+
+	left, right := tr.SplitWhere(func(value int, index int) bool { return value < 10 })
+	requireTreapValues(t, left, 1, 4, 4, 9)
+	requireTreapValues(t, right, 12, 20)
+	require.True(t, tr.Empty())
+
+	// Index-based predicates work too.
+	first, rest := right.SplitWhere(func(value int, index int) bool { return index < 1 })
+	requireTreapValues(t, first, 12)
+	requireTreapValues(t, rest, 20)
+}
+
+func TestCountAndFindWhere(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 1, 4, 4, 9, 12, 20)
+	small := func(value int, index int) bool { return value < 5 }
+	big := func(value int, index int) bool { return value > 5 }
+
+	require.Equal(t, 3, tr.CountWhere(small))
+	require.Equal(t, 0, tr.CountWhere(func(int, int) bool { return false }))
+	require.Equal(t, 6, tr.CountWhere(func(int, int) bool { return true }))
+
+	node, idx := tr.FindLastWhere(small)
+	require.Equal(t, 4, node.Value())
+	require.Equal(t, 2, idx)
+	node, _ = tr.FindLastWhere(func(int, int) bool { return false })
+	require.Nil(t, node)
+
+	node, idx = tr.FindFirstWhere(big)
+	require.Equal(t, 9, node.Value())
+	require.Equal(t, 3, idx)
+	node, _ = tr.FindFirstWhere(func(int, int) bool { return false })
+	require.Nil(t, node)
+	node, idx = tr.FindFirstWhere(func(int, int) bool { return true })
+	require.Equal(t, 1, node.Value())
+	require.Equal(t, 0, idx)
+}
+
+func TestWherePredicatesMonotonicityCheck(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 1, 2, 3, 4)
+	even := func(value int, index int) bool { return value%2 == 0 }
+
+	if !debugMode {
+		// Outside debug builds the result for a non-monotone predicate is unspecified but must not panic.
+		require.NotPanics(t, func() { tr.CountWhere(even) })
+		return
+	}
+
+	require.Panics(t, func() { tr.CountWhere(even) })
+	require.Panics(t, func() { tr.FindLastWhere(even) })
+	require.Panics(t, func() { tr.FindFirstWhere(even) })
+	require.Panics(t, func() { tr.SplitWhere(even) })
+	// A prefix predicate is not a valid suffix predicate and vice versa.
+	require.Panics(t, func() { tr.FindFirstWhere(func(value int, index int) bool { return value < 3 }) })
+	require.Panics(t, func() { tr.CountWhere(func(value int, index int) bool { return value > 2 }) })
+	require.NotPanics(t, func() { tr.FindFirstWhere(func(value int, index int) bool { return value > 2 }) })
+}
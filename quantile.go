@@ -0,0 +1,100 @@
+package gotreap
+
+import "math"
+
+// Rank returns the number of elements strictly less than value in O(log n).
+func (t *Treap[T]) Rank(value T) int {
+	return t.prefixLen(t.condLess(value))
+}
+
+// quantilePosition maps q to a fractional index into a treap of size n > 0.
+// Decimal quantiles such as 0.29 have no exact binary form, so q*(n-1) may land a few ULPs off the
+// intended integer (0.29*100 is 28.999999999999996); positions that close to an integer snap to it
+// before any rounding. Panics if q is outside [0, 1].
+func quantilePosition(q float64, n int) float64 {
+	if !(q >= 0 && q <= 1) {
+		panic("quantile must be within [0, 1]")
+	}
+	pos := q * float64(n-1)
+	if nearest := math.Round(pos); math.Abs(pos-nearest) <= 4*pos*0x1p-52 {
+		return nearest
+	}
+	return pos
+}
+
+// quantile returns the element at the index obtained by rounding the position of q with round.
+func (t *Treap[T]) quantile(q float64, round func(float64) float64) (value T, ok bool) {
+	sz := t.Size()
+	if sz == 0 {
+		quantilePosition(q, 1) // still reject an invalid q
+		return value, false
+	}
+	return t.At(int(round(quantilePosition(q, sz)))).value, true
+}
+
+// Quantile returns the lower q-quantile: the element at index floor(q*(n-1)) in O(log n).
+// A product a few ULPs off an integer counts as that integer, so Quantile(0.29) of 101 elements is index 29.
+// The ok flag is false when the treap is empty. Panics if q is outside [0, 1].
+func (t *Treap[T]) Quantile(q float64) (value T, ok bool) {
+	return t.quantile(q, math.Floor)
+}
+
+// QuantileUpper returns the upper q-quantile: the element at index ceil(q*(n-1)) in O(log n).
+// The ok flag is false when the treap is empty. Panics if q is outside [0, 1].
+func (t *Treap[T]) QuantileUpper(q float64) (value T, ok bool) {
+	return t.quantile(q, math.Ceil)
+}
+
+// Percentile returns the lower p-th percentile, see Quantile. Panics if p is outside [0, 100].
+func (t *Treap[T]) Percentile(p float64) (value T, ok bool) {
+	return t.Quantile(p / 100)
+}
+
+// PercentileUpper returns the upper p-th percentile, see QuantileUpper. Panics if p is outside [0, 100].
+func (t *Treap[T]) PercentileUpper(p float64) (value T, ok bool) {
+	return t.QuantileUpper(p / 100)
+}
+
+// Median returns the lower median, the smaller middle element for even sizes.
+// The ok flag is false when the treap is empty.
+func (t *Treap[T]) Median() (value T, ok bool) {
+	return t.Quantile(0.5)
+}
+
+// MedianUpper returns the upper median, the larger middle element for even sizes.
+// The ok flag is false when the treap is empty.
+func (t *Treap[T]) MedianUpper() (value T, ok bool) {
+	return t.QuantileUpper(0.5)
+}
+
+// QuantileInterpolated returns the q-quantile of a numeric treap, linearly interpolating
+// between the elements around position q*(n-1). Runs in O(log n).
+// The ok flag is false when the treap is empty. Panics if q is outside [0, 1].
+func QuantileInterpolated[T Number](t *Treap[T], q float64) (value float64, ok bool) {
+	sz := t.Size()
+	if sz == 0 {
+		quantilePosition(q, 1) // still reject an invalid q
+		return 0, false
+	}
+
+	pos := quantilePosition(q, sz)
+	lowerIndex := math.Floor(pos)
+	lower := t.At(int(lowerIndex))
+	if frac := pos - lowerIndex; frac > 0 {
+		upper := lower.Next()
+		return float64(lower.value) + (float64(upper.value)-float64(lower.value))*frac, true
+	}
+	return float64(lower.value), true
+}
+
+// PercentileInterpolated returns the interpolated p-th percentile, see QuantileInterpolated.
+// Panics if p is outside [0, 100].
+func PercentileInterpolated[T Number](t *Treap[T], p float64) (value float64, ok bool) {
+	return QuantileInterpolated(t, p/100)
+}
+
+// MedianInterpolated returns the median of a numeric treap, averaging the two middle elements for even sizes.
+// The ok flag is false when the treap is empty.
+func MedianInterpolated[T Number](t *Treap[T]) (value float64, ok bool) {
+	return QuantileInterpolated(t, 0.5)
+}
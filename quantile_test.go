@@ -0,0 +1,104 @@
+package gotreap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRank(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 10, 20, 20, 30)
+
+	require.Equal(t, 0, tr.Rank(5))
+	require.Equal(t, 0, tr.Rank(10))
+	require.Equal(t, 1, tr.Rank(20))
+	require.Equal(t, 3, tr.Rank(25))
+	require.Equal(t, 4, tr.Rank(31))
+	require.Equal(t, 0, NewAutoOrderTreap[int]().Rank(1))
+}
+
+func TestQuantileVariants(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 40, 10, 30, 20)
+
+	v, ok := tr.Quantile(0)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+	v, _ = tr.Quantile(1)
+	require.Equal(t, 40, v)
+	v, _ = tr.Quantile(0.5)
+	require.Equal(t, 20, v)
+	v, _ = tr.QuantileUpper(0.5)
+	require.Equal(t, 30, v)
+
+	v, _ = tr.Percentile(90)
+	require.Equal(t, 30, v)
+	v, _ = tr.PercentileUpper(90)
+	require.Equal(t, 40, v)
+
+	v, _ = tr.Median()
+	require.Equal(t, 20, v)
+	v, _ = tr.MedianUpper()
+	require.Equal(t, 30, v)
+
+	f, ok := MedianInterpolated(tr)
+	require.True(t, ok)
+	require.Equal(t, 25.0, f)
+	f, _ = QuantileInterpolated(tr, 1.0/3)
+	require.InDelta(t, 20.0, f, 1e-9)
+	f, _ = PercentileInterpolated(tr, 90)
+	require.InDelta(t, 37.0, f, 1e-9)
+	f, _ = QuantileInterpolated(tr, 1)
+	require.Equal(t, 40.0, f)
+
+	tr.InsertRight(50)
+	v, _ = tr.Median()
+	require.Equal(t, 30, v)
+	v, _ = tr.MedianUpper()
+	require.Equal(t, 30, v)
+}
+
+func TestQuantileDecimalPositions(t *testing.T) {
+	values := make([]int, 101)
+	for i := range values {
+		values[i] = i
+	}
+	tr := NewAutoOrderTreapWithRand(staticRand(), values...)
+
+	// 0.29*100 and 0.57*100 fall just below 29 and 57, 0.07*100 just above 7.
+	v, _ := tr.Quantile(0.29)
+	require.Equal(t, 29, v)
+	v, _ = tr.Quantile(0.57)
+	require.Equal(t, 57, v)
+	v, _ = tr.QuantileUpper(0.07)
+	require.Equal(t, 7, v)
+	f, _ := QuantileInterpolated(tr, 0.29)
+	require.Equal(t, 29.0, f)
+
+	for p := range 101 {
+		v, _ = tr.Percentile(float64(p))
+		require.Equal(t, p, v)
+		v, _ = tr.PercentileUpper(float64(p))
+		require.Equal(t, p, v)
+	}
+	v, _ = tr.Quantile(0.295)
+	require.Equal(t, 29, v)
+	v, _ = tr.QuantileUpper(0.295)
+	require.Equal(t, 30, v)
+}
+
+func TestQuantileEdgeCases(t *testing.T) {
+	empty := NewAutoOrderTreap[float64]()
+	_, ok := empty.Median()
+	require.False(t, ok)
+	_, ok = QuantileInterpolated(empty, 0.3)
+	require.False(t, ok)
+
+	single := NewAutoOrderTreapWithRand(staticRand(), 7.5)
+	v, ok := single.Percentile(99)
+	require.True(t, ok)
+	require.Equal(t, 7.5, v)
+
+	require.Panics(t, func() { single.Quantile(-0.1) })
+	require.Panics(t, func() { single.Percentile(101) })
+	require.Panics(t, func() { QuantileInterpolated(empty, 2) })
+}
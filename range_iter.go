@@ -0,0 +1,93 @@
+package gotreap
+
+import "iter"
+
+// requireValidRange panics on the value bounds rejected by CountRange.
+func (t *Treap[T]) requireValidRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) {
+	if t.lessFn(endValue, startValue) {
+		panic("provided endValue must not be lower than startValue")
+	}
+	if !t.lessFn(startValue, endValue) && (!inclusiveStart || !inclusiveEnd) {
+		panic("when startValue == endValue, both start and end must be inclusive")
+	}
+}
+
+// valueRangeIndices converts value bounds into the index range [from, to) they cover,
+// with the same inclusivity rules and panics as CountRange. to may be below from for an empty range.
+func (t *Treap[T]) valueRangeIndices(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) (from int, to int) {
+	t.requireValidRange(startValue, inclusiveStart, endValue, inclusiveEnd)
+
+	if inclusiveStart {
+		from = t.prefixLen(t.condLess(startValue))
+	} else {
+		from = t.prefixLen(t.condLeq(startValue))
+	}
+	if inclusiveEnd {
+		to = t.prefixLen(t.condLeq(endValue))
+	} else {
+		to = t.prefixLen(t.condLess(endValue))
+	}
+	return from, to
+}
+
+// walkIndexRange yields the index and value of every element in [from, to), walking backwards if requested.
+// Bounds must already be clamped to the treap.
+func (t *Treap[T]) walkIndexRange(from int, to int, backwards bool, yield func(int, T) bool) {
+	if from >= to {
+		return
+	}
+	if backwards {
+		cur := t.At(to - 1)
+		for i := to - 1; i >= from; i-- {
+			if !yield(i, cur.value) {
+				return
+			}
+			cur = cur.Prev()
+		}
+		return
+	}
+	cur := t.At(from)
+	for i := from; i < to; i++ {
+		if !yield(i, cur.value) {
+			return
+		}
+		cur = cur.Next()
+	}
+}
+
+// Iterate over values between startValue and endValue (in ascending order).
+// Each bound is included only when its inclusive flag is true, matching CountRange and EraseRange.
+// Panics if endValue < startValue, or if startValue == endValue with non-inclusive bounds.
+func (t *Treap[T]) ValuesInRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) iter.Seq[T] {
+	return t.valuesInRange(startValue, inclusiveStart, endValue, inclusiveEnd, false)
+}
+
+// Iterate over values between startValue and endValue in reverse order, see ValuesInRange.
+func (t *Treap[T]) ValuesInRangeBackwards(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) iter.Seq[T] {
+	return t.valuesInRange(startValue, inclusiveStart, endValue, inclusiveEnd, true)
+}
+
+func (t *Treap[T]) valuesInRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool, backwards bool) iter.Seq[T] {
+	t.requireValidRange(startValue, inclusiveStart, endValue, inclusiveEnd)
+	return func(yield func(T) bool) {
+		from, to := t.valueRangeIndices(startValue, inclusiveStart, endValue, inclusiveEnd)
+		t.walkIndexRange(from, to, backwards, func(_ int, value T) bool {
+			return yield(value)
+		})
+	}
+}
+
+// Iterate over index/value pairs with indices in [from, to) (in ascending order).
+// Indices are clamped to the treap bounds, matching AggregateIndexRange.
+func (t *Treap[T]) ValuesBetweenIndices(from int, to int) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		t.walkIndexRange(max(from, 0), min(to, t.Size()), false, yield)
+	}
+}
+
+// Iterate over index/value pairs with indices in [from, to) in reverse order, see ValuesBetweenIndices.
+func (t *Treap[T]) ValuesBetweenIndicesBackwards(from int, to int) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		t.walkIndexRange(max(from, 0), min(to, t.Size()), true, yield)
+	}
+}
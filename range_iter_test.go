@@ -0,0 +1,92 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func collectPairs[T any](t *testing.T, tr *Treap[T], from, to int, backwards bool) ([]int, []T) {
+	t.Helper()
+	seq := tr.ValuesBetweenIndices(from, to)
+	if backwards {
+		seq = tr.ValuesBetweenIndicesBackwards(from, to)
+	}
+	var indices []int
+	var values []T
+	for i, v := range seq {
+		indices = append(indices, i)
+		values = append(values, v)
+	}
+	return indices, values
+}
+
+func TestValuesInRange(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 1, 3, 3, 5, 7, 7, 9)
+
+	require.Equal(t, []int{3, 3, 5}, slices.Collect(tr.ValuesInRange(3, true, 7, false)))
+	require.Equal(t, []int{5, 7, 7}, slices.Collect(tr.ValuesInRange(3, false, 7, true)))
+	require.Equal(t, []int{7, 7, 5}, slices.Collect(tr.ValuesInRangeBackwards(3, false, 7, true)))
+	require.Equal(t, []int{3, 3}, slices.Collect(tr.ValuesInRange(3, true, 3, true)))
+	require.Empty(t, slices.Collect(tr.ValuesInRange(3, false, 5, false)))
+	require.Empty(t, slices.Collect(tr.ValuesInRange(10, true, 20, true)))
+	require.Equal(t, []int{9, 7, 7, 5, 3, 3, 1}, slices.Collect(tr.ValuesInRangeBackwards(0, true, 100, true)))
+
+	require.Panics(t, func() { tr.ValuesInRange(5, true, 4, true) })
+	require.Panics(t, func() { tr.ValuesInRangeBackwards(5, true, 5, false) })
+
+	var firstTwo []int
+	for v := range tr.ValuesInRange(1, true, 9, true) {
+		firstTwo = append(firstTwo, v)
+		if len(firstTwo) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []int{1, 3}, firstTwo)
+}
+
+func TestValuesBetweenIndices(t *testing.T) {
+	tr := NewAutoOrderTreapWithRand(staticRand(), 10, 20, 30, 40, 50)
+
+	indices, values := collectPairs(t, tr, 1, 4, false)
+	require.Equal(t, []int{1, 2, 3}, indices)
+	require.Equal(t, []int{20, 30, 40}, values)
+
+	indices, values = collectPairs(t, tr, 1, 4, true)
+	require.Equal(t, []int{3, 2, 1}, indices)
+	require.Equal(t, []int{40, 30, 20}, values)
+
+	// Bounds are clamped.
+	_, values = collectPairs(t, tr, -5, 100, false)
+	require.Equal(t, []int{10, 20, 30, 40, 50}, values)
+	indices, _ = collectPairs(t, tr, 3, 2, false)
+	require.Empty(t, indices)
+	indices, _ = collectPairs(t, NewAutoOrderTreap[int](), 0, 5, true)
+	require.Empty(t, indices)
+}
+
+func TestRangeIteratorsMatchCountRange(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(21, 12))
+	tr := NewAutoOrderTreapWithRand[int](staticRand())
+	for range 300 {
+		tr.InsertRight(rnd.IntN(60))
+	}
+
+	for range 200 {
+		a := rnd.IntN(70) - 5
+		b := a + rnd.IntN(20)
+		inclStart, inclEnd := rnd.IntN(2) == 0, rnd.IntN(2) == 0
+		if a == b {
+			inclStart, inclEnd = true, true
+		}
+
+		forward := slices.Collect(tr.ValuesInRange(a, inclStart, b, inclEnd))
+		require.Len(t, forward, tr.CountRange(a, inclStart, b, inclEnd))
+
+		backward := slices.Collect(tr.ValuesInRangeBackwards(a, inclStart, b, inclEnd))
+		slices.Reverse(backward)
+		require.Equal(t, forward, backward)
+	}
+}
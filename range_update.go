@@ -0,0 +1,44 @@
+package gotreap
+
+// Number is the set of types supporting arithmetic range updates.
+type Number interface {
+	Signed | Unsigned | ~float32 | ~float64
+}
+
+// rangeUpdate is a pending modification applied lazily to every value of a subtree.
+type rangeUpdate[T any] interface {
+	// apply returns value with the update applied.
+	apply(value T) T
+	// then returns a single update equivalent to the receiver followed by next.
+	then(next rangeUpdate[T]) rangeUpdate[T]
+}
+
+// addUpdate adds delta to every value.
+type addUpdate[T Number] struct {
+	delta T
+}
+
+func (u addUpdate[T]) apply(value T) T {
+	return value + u.delta
+}
+
+func (u addUpdate[T]) then(next rangeUpdate[T]) rangeUpdate[T] {
+	if add, ok := next.(addUpdate[T]); ok {
+		return addUpdate[T]{delta: u.delta + add.delta}
+	}
+	// Every other update overwrites values, discarding the receiver.
+	return next
+}
+
+// assignUpdate replaces every value with value.
+type assignUpdate[T any] struct {
+	value T
+}
+
+func (u assignUpdate[T]) apply(T) T {
+	return u.value
+}
+
+func (u assignUpdate[T]) then(next rangeUpdate[T]) rangeUpdate[T] {
+	return assignUpdate[T]{value: next.apply(u.value)}
+}
@@ -0,0 +1,262 @@
+package gotreap
+
+import (
+	"iter"
+	"math/rand/v2"
+)
+
+// Sequence is an implicit-key treap: elements are addressed purely by position,
+// so it behaves like an array list with O(log n) insertion and removal anywhere.
+// Range updates such as Reverse and Assign are applied lazily in O(log n).
+type Sequence[T any] struct {
+	randFn func() int
+	traits *nodeTraits[T]
+	root   *Node[T]
+}
+
+// NewSequence constructs a sequence holding values in the given order.
+func NewSequence[T any](values ...T) *Sequence[T] {
+	return NewSequenceWithRand(rand.Int, values...)
+}
+
+// NewSequenceWithRand constructs a sequence holding values in the given order using randFn for tree balancing.
+func NewSequenceWithRand[T any](randFn func() int, values ...T) *Sequence[T] {
+	if randFn == nil {
+		panic("randFn must not be nil")
+	}
+
+	s := &Sequence[T]{
+		randFn: randFn,
+		traits: &nodeTraits[T]{lazy: true},
+		root:   nil,
+	}
+
+	for _, val := range values {
+		s.root = merge(s.root, s.newNode(val))
+	}
+
+	return s
+}
+
+// newNode creates a detached node for value that shares the sequence's traits.
+func (s *Sequence[T]) newNode(value T) *Node[T] {
+	return newExtNode(value, s.randFn(), s.traits)
+}
+
+// withRoot creates a sequence sharing s's configuration with the provided root.
+func (s *Sequence[T]) withRoot(root *Node[T]) *Sequence[T] {
+	return &Sequence[T]{
+		randFn: s.randFn,
+		traits: s.traits,
+		root:   root,
+	}
+}
+
+// updateRange applies fn to the subtree holding the elements in [i, j).
+// Panics unless 0 <= i <= j <= Size().
+func (s *Sequence[T]) updateRange(i, j int, fn func(middle *Node[T])) {
+	if i < 0 || j < i || j > s.root.safeSize() {
+		panic("range bounds out of range")
+	}
+
+	left, right := s.root.split(condCutN[T](i), 0)
+	middle, right := right.split(condCutN[T](j-i), 0)
+
+	fn(middle)
+
+	s.root = merge(left, merge(middle, right))
+}
+
+// Reverse reverses the order of the elements in [i, j).
+// Panics unless 0 <= i <= j <= Size().
+func (s *Sequence[T]) Reverse(i, j int) {
+	s.updateRange(i, j, (*Node[T]).applyReverse)
+}
+
+// Assign replaces every element in [i, j) with value.
+// Panics unless 0 <= i <= j <= Size().
+func (s *Sequence[T]) Assign(i, j int, value T) {
+	s.updateRange(i, j, func(middle *Node[T]) {
+		middle.applyUpdate(assignUpdate[T]{value: value})
+	})
+}
+
+// AddRange adds delta to every element of s in [i, j).
+// Panics unless 0 <= i <= j <= s.Size().
+func AddRange[T Number](s *Sequence[T], i, j int, delta T) {
+	s.updateRange(i, j, func(middle *Node[T]) {
+		middle.applyUpdate(addUpdate[T]{delta: delta})
+	})
+}
+
+// InsertAt inserts value so that it ends up at index, shifting later elements right.
+// Panics if index is outside [0, Size()].
+func (s *Sequence[T]) InsertAt(index int, value T) {
+	if index < 0 || index > s.root.safeSize() {
+		panic("index out of range")
+	}
+
+	left, right := s.root.split(condCutN[T](index), 0)
+	s.root = merge(merge(left, s.newNode(value)), right)
+}
+
+// PushBack appends value to the end of the sequence.
+func (s *Sequence[T]) PushBack(value T) {
+	s.root = merge(s.root, s.newNode(value))
+}
+
+// PushFront prepends value to the beginning of the sequence.
+func (s *Sequence[T]) PushFront(value T) {
+	s.root = merge(s.newNode(value), s.root)
+}
+
+// EraseAt removes up to count elements starting at index and returns how many were erased.
+// Supports negative indexing where -1 refers to the last element.
+// Panics if count is negative.
+func (s *Sequence[T]) EraseAt(index int, count int) (erasedCount int) {
+	if count < 0 {
+		panic("count must not be negative")
+	}
+
+	sz := s.root.safeSize()
+	if index < 0 {
+		index = sz + index
+	}
+	if index < 0 || index >= sz {
+		return 0
+	}
+
+	left, right := s.root.split(condCutN[T](index), 0)
+	toErase, right := right.split(condCutN[T](count), 0)
+
+	s.root = merge(left, right)
+
+	return toErase.safeSize()
+}
+
+// At returns the node located at the provided index or nil if it is out of range.
+// Supports negative indexing where -1 refers to the last element.
+func (s *Sequence[T]) At(index int) *Node[T] {
+	sz := s.root.safeSize()
+	if sz == 0 || index < -sz || index >= sz {
+		return nil
+	}
+	if index < 0 {
+		index = sz + index
+	}
+
+	node, _ := s.root.lookupLeftmostUnmatch(condCutN[T](index), 0)
+	return node
+}
+
+// Set replaces the element at index with value and reports whether index was in range.
+// Supports negative indexing where -1 refers to the last element.
+func (s *Sequence[T]) Set(index int, value T) (ok bool) {
+	node := s.At(index)
+	if node == nil {
+		return false
+	}
+	node.value = value
+	return true
+}
+
+// Slice returns a new sequence holding copies of the elements in [i, j), leaving s unchanged.
+// Panics unless 0 <= i <= j <= Size().
+func (s *Sequence[T]) Slice(i, j int) *Sequence[T] {
+	var result *Sequence[T]
+	s.updateRange(i, j, func(middle *Node[T]) {
+		result = s.withRoot(middle.clone())
+	})
+	return result
+}
+
+// Concat appends all elements of other to the end of s. The other sequence is consumed.
+func (s *Sequence[T]) Concat(other *Sequence[T]) {
+	if other == nil || other == s {
+		return
+	}
+	s.root = merge(s.root, other.root)
+	other.root = nil
+}
+
+// SplitAt splits the sequence into the first index elements and the remainder and clears the receiver.
+// If index is negative, cuts from the end like Treap.Cut.
+func (s *Sequence[T]) SplitAt(index int) (left *Sequence[T], right *Sequence[T]) {
+	if index < 0 {
+		index = max(s.root.safeSize()+index, 0)
+	}
+
+	l, r := s.root.split(condCutN[T](index), 0)
+
+	s.root = nil
+
+	return s.withRoot(l), s.withRoot(r)
+}
+
+// Size reports the number of elements stored in the sequence.
+func (s *Sequence[T]) Size() int {
+	return s.root.safeSize()
+}
+
+// Empty reports whether the sequence contains no elements.
+func (s *Sequence[T]) Empty() bool {
+	return s.root.safeSize() == 0
+}
+
+// Clear removes all elements from the sequence.
+func (s *Sequence[T]) Clear() {
+	s.root = nil
+}
+
+// walkInOrder visits the nodes of the subtree rooted at root in order, or in reverse order when backwards is set,
+// pushing pending range updates down on the way so every visited node is current.
+func walkInOrder[T any](root *Node[T], backwards bool, yield func(*Node[T]) bool) {
+	var stack []*Node[T]
+	for cur := root; cur != nil || len(stack) > 0; {
+		for cur != nil {
+			cur.push()
+			stack = append(stack, cur)
+			if backwards {
+				cur = cur.right
+			} else {
+				cur = cur.left
+			}
+		}
+
+		cur, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		if !yield(cur) {
+			return
+		}
+
+		if backwards {
+			cur = cur.left
+		} else {
+			cur = cur.right
+		}
+	}
+}
+
+// Iterate over sequence elements (first to last)
+func (s *Sequence[T]) Elements() iter.Seq[*Node[T]] {
+	return func(yield func(*Node[T]) bool) {
+		walkInOrder(s.root, false, yield)
+	}
+}
+
+// Iterate over sequence values (first to last)
+func (s *Sequence[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		walkInOrder(s.root, false, func(node *Node[T]) bool {
+			return yield(node.value)
+		})
+	}
+}
+
+// Iterate over sequence values in reverse order (last to first)
+func (s *Sequence[T]) ValuesBackwards() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		walkInOrder(s.root, true, func(node *Node[T]) bool {
+			return yield(node.value)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireSequenceValues[T any](t *testing.T, s *Sequence[T], expected ...T) {
+	t.Helper()
+	values := slices.Collect(s.Values())
+	if values == nil {
+		values = []T{}
+	}
+	if expected == nil {
+		expected = []T{}
+	}
+	require.Equal(t, expected, values)
+}
+
+func TestSequenceKeepsInsertionOrder(t *testing.T) {
+	s := NewSequenceWithRand(staticRand(), "c", "a", "b")
+	requireSequenceValues(t, s, "c", "a", "b")
+
+	s.InsertAt(1, "x")
+	s.InsertAt(0, "first")
+	s.InsertAt(s.Size(), "last")
+	s.PushBack("back")
+	s.PushFront("front")
+	requireSequenceValues(t, s, "front", "first", "c", "x", "a", "b", "last", "back")
+
+	require.Panics(t, func() { s.InsertAt(-1, "bad") })
+	require.Panics(t, func() { s.InsertAt(s.Size()+1, "bad") })
+}
+
+func TestSequenceAtSetErase(t *testing.T) {
+	s := NewSequenceWithRand(staticRand(), 1, 2, 3, 4, 5)
+
+	require.Equal(t, 5, s.At(-1).Value())
+	require.Nil(t, s.At(5))
+	require.Equal(t, 2, s.At(2).Index())
+
+	require.True(t, s.Set(0, 10))
+	require.True(t, s.Set(-1, 50))
+	require.False(t, s.Set(7, 70))
+	requireSequenceValues(t, s, 10, 2, 3, 4, 50)
+
+	require.Equal(t, 2, s.EraseAt(1, 2))
+	requireSequenceValues(t, s, 10, 4, 50)
+	require.Equal(t, 1, s.EraseAt(-1, 10))
+	require.Equal(t, 0, s.EraseAt(5, 1))
+	requireSequenceValues(t, s, 10, 4)
+
+	require.Panics(t, func() { s.EraseAt(0, -1) })
+}
+
+func TestSequenceSliceLeavesOriginal(t *testing.T) {
+	s := NewSequenceWithRand(staticRand(), 1, 2, 3, 4, 5)
+
+	sub := s.Slice(1, 4)
+	requireSequenceValues(t, sub, 2, 3, 4)
+	requireSequenceValues(t, s, 1, 2, 3, 4, 5)
+
+	sub.Set(0, 20)
+	requireSequenceValues(t, s, 1, 2, 3, 4, 5)
+	require.True(t, s.Slice(2, 2).Empty())
+
+	require.Panics(t, func() { s.Slice(3, 2) })
+	require.Panics(t, func() { s.Slice(0, 6) })
+}
+
+func TestSequenceConcatAndSplitAt(t *testing.T) {
+	s := NewSequenceWithRand(staticRand(), 5, 4, 3)
+	other := NewSequenceWithRand(staticRand(), 2, 1)
+
+	s.Concat(other)
+	require.True(t, other.Empty())
+	requireSequenceValues(t, s, 5, 4, 3, 2, 1)
+	s.Concat(s)
+	requireSequenceValues(t, s, 5, 4, 3, 2, 1)
+
+	left, right := s.SplitAt(2)
+	require.True(t, s.Empty())
+	requireSequenceValues(t, left, 5, 4)
+	requireSequenceValues(t, right, 3, 2, 1)
+
+	left.Concat(right)
+	left, right = left.SplitAt(-4)
+	requireSequenceValues(t, left, 5)
+	requireSequenceValues(t, right, 4, 3, 2, 1)
+	require.Equal(t, []int{1, 2, 3, 4}, slices.Collect(right.ValuesBackwards()))
+
+	left, right = right.SplitAt(-10)
+	require.True(t, left.Empty())
+	require.Equal(t, 4, right.Size())
+
+	right.Clear()
+	require.True(t, right.Empty())
+}
+
+func TestSequenceMatchesSlice(t *testing.T) {
+	s := NewSequenceWithRand[int](staticRand())
+	var expected []int
+
+	rnd := rand.New(rand.NewPCG(5, 6))
+	for i := range 2000 {
+		switch op := rnd.IntN(4); {
+		case op < 2 || len(expected) == 0:
+			pos := rnd.IntN(len(expected) + 1)
+			s.InsertAt(pos, i)
+			expected = slices.Insert(expected, pos, i)
+		case op == 2:
+			pos := rnd.IntN(len(expected))
+			count := rnd.IntN(3)
+			s.EraseAt(pos, count)
+			expected = slices.Delete(expected, pos, min(pos+count, len(expected)))
+		default:
+			pos := rnd.IntN(len(expected))
+			s.Set(pos, -i)
+			expected[pos] = -i
+		}
+	}
+
+	requireSequenceValues(t, s, expected...)
+	for node := range s.Elements() {
+		require.Equal(t, expected[node.Index()], node.Value())
+	}
+}
+
+func TestSequenceReverseAssignAdd(t *testing.T) {
+	s := NewSequenceWithRand(staticRand(), 1, 2, 3, 4, 5, 6)
+
+	s.Reverse(1, 5)
+	requireSequenceValues(t, s, 1, 5, 4, 3, 2, 6)
+
+	AddRange(s, 0, 3, 10)
+	requireSequenceValues(t, s, 11, 15, 14, 3, 2, 6)
+
+	s.Assign(2, 4, 0)
+	requireSequenceValues(t, s, 11, 15, 0, 0, 2, 6)
+
+	AddRange(s, 3, 6, 1)
+	s.Reverse(0, 6)
+	requireSequenceValues(t, s, 7, 3, 1, 0, 15, 11)
+	require.Equal(t, []int{11, 15, 0, 1, 3, 7}, slices.Collect(s.ValuesBackwards()))
+
+	s.Reverse(2, 2)
+	require.Panics(t, func() { s.Reverse(3, 2) })
+	require.Panics(t, func() { s.Assign(0, 7, 1) })
+	require.Panics(t, func() { AddRange(s, -1, 2, 1) })
+}
+
+func TestSequenceNavigationWithPendingUpdates(t *testing.T) {
+	s := NewSequenceWithRand(staticRand(), 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	nodes := slices.Collect(s.Elements())
+
+	// Updates stay pending on the subtree roots produced by the splits.
+	s.Reverse(2, 8)
+	AddRange(s, 0, 10, 100)
+	expected := []int{100, 101, 107, 106, 105, 104, 103, 102, 108, 109}
+
+	for _, node := range nodes {
+		idx := node.Index()
+		require.Equal(t, expected[idx], node.Value())
+		if idx > 0 {
+			require.Equal(t, expected[idx-1], node.Prev().Value())
+		} else {
+			require.Nil(t, node.Prev())
+		}
+		if idx < len(expected)-1 {
+			require.Equal(t, expected[idx+1], node.Next().Value())
+		} else {
+			require.Nil(t, node.Next())
+		}
+		for target := range expected {
+			require.Equal(t, expected[target], node.JumpRight(target-idx).Value())
+		}
+		require.Equal(t, expected[0], node.Leftmost().Value())
+		require.Equal(t, expected[len(expected)-1], node.Rightmost().Value())
+	}
+
+	s.Reverse(0, 10)
+	last := s.At(-1)
+	require.Equal(t, 100, last.Value())
+	require.Equal(t, 9, last.Index())
+}
+
+func TestSequenceRangeUpdatesMatchSlice(t *testing.T) {
+	s := NewSequenceWithRand[int](staticRand())
+	var expected []int
+
+	rnd := rand.New(rand.NewPCG(7, 8))
+	for i := range 3000 {
+		op := rnd.IntN(6)
+		if op == 0 || len(expected) == 0 {
+			pos := rnd.IntN(len(expected) + 1)
+			s.InsertAt(pos, i)
+			expected = slices.Insert(expected, pos, i)
+			continue
+		}
+
+		from := rnd.IntN(len(expected) + 1)
+		to := from + rnd.IntN(len(expected)-from+1)
+		switch op {
+		case 1, 2:
+			s.Reverse(from, to)
+			slices.Reverse(expected[from:to])
+		case 3:
+			AddRange(s, from, to, i)
+			for k := from; k < to; k++ {
+				expected[k] += i
+			}
+		case 4:
+			s.Assign(from, to, -i)
+			for k := from; k < to; k++ {
+				expected[k] = -i
+			}
+		default:
+			requireSequenceValues(t, s.Slice(from, to), expected[from:to]...)
+		}
+
+		if i%100 == 0 {
+			pos := rnd.IntN(len(expected))
+			require.Equal(t, expected[pos], s.At(pos).Value())
+		}
+	}
+
+	requireSequenceValues(t, s, expected...)
+}
@@ -0,0 +1,211 @@
+package gotreap
+
+// Multiplicity selects how occurrence counts of a value present in both operands
+// of Union or Intersection are combined.
+type Multiplicity int
+
+const (
+	// MultiplicitySum keeps every occurrence from both operands.
+	MultiplicitySum Multiplicity = iota
+	// MultiplicityMin keeps as many occurrences as the operand holding fewer of them.
+	MultiplicityMin
+	// MultiplicityMax keeps as many occurrences as the operand holding more of them.
+	MultiplicityMax
+)
+
+// setOperation describes a set algebra operation in terms of how it treats
+// values found in only one operand and runs of equal values found in both.
+type setOperation[T any] struct {
+	keepOnlyLeft  bool
+	keepOnlyRight bool
+	combineEqual  func(left, right *Node[T]) *Node[T]
+}
+
+// takeFirst returns the first n nodes of run, discarding the rest.
+func takeFirst[T any](run *Node[T], n int) *Node[T] {
+	taken, _ := run.split(condCutN[T](n), 0)
+	return taken
+}
+
+// combineByMultiplicity returns a run combiner implementing mode for runs of equal values.
+func combineByMultiplicity[T any](mode Multiplicity) func(left, right *Node[T]) *Node[T] {
+	switch mode {
+	case MultiplicitySum:
+		return merge[T]
+	case MultiplicityMin:
+		return func(left, right *Node[T]) *Node[T] {
+			if left.safeSize() <= right.safeSize() {
+				return left
+			}
+			return right
+		}
+	case MultiplicityMax:
+		return func(left, right *Node[T]) *Node[T] {
+			if left.safeSize() >= right.safeSize() {
+				return left
+			}
+			return right
+		}
+	default:
+		panic("unknown multiplicity mode")
+	}
+}
+
+// apply runs op over the subtrees left and right using the classic split-based divide and conquer.
+// The higher priority root becomes the pivot, both subtrees are split around its value and the
+// halves are processed recursively, so the work is O(m log(n/m)) for operands of sizes m <= n.
+func (t *Treap[T]) apply(op *setOperation[T], left *Node[T], right *Node[T]) *Node[T] {
+	if left == nil {
+		if op.keepOnlyRight {
+			return right
+		}
+		return nil
+	}
+	if right == nil {
+		if op.keepOnlyLeft {
+			return left
+		}
+		return nil
+	}
+
+	pivot := left.value
+	if right.heightPriority > left.heightPriority {
+		pivot = right.value
+	}
+
+	leftLess, leftRest := left.split(t.condLess(pivot), 0)
+	leftEqual, leftGreater := leftRest.split(t.condLeq(pivot), 0)
+	rightLess, rightRest := right.split(t.condLess(pivot), 0)
+	rightEqual, rightGreater := rightRest.split(t.condLeq(pivot), 0)
+
+	// A treap without duplicates keeps a single element of a value, see survivor.
+	var keep *Node[T]
+	if t.duplicates != DuplicatesAllow {
+		keep = t.survivor(leftEqual, rightEqual)
+	}
+
+	var equal *Node[T]
+	switch {
+	case leftEqual == nil:
+		equal = t.apply(op, nil, rightEqual)
+	case rightEqual == nil:
+		equal = t.apply(op, leftEqual, nil)
+	default:
+		largest := max(leftEqual.safeSize(), rightEqual.safeSize())
+		equal = op.combineEqual(leftEqual, rightEqual)
+		// A run assembled from both operands continues the ranks of the left one on the right.
+		if keep == nil && t.Canonical() && equal.safeSize() > largest {
+			equal = t.rebuildRun(equal)
+		}
+	}
+	if keep != nil && equal != nil {
+		equal = t.isolate(keep)
+	}
+
+	less := t.apply(op, leftLess, rightLess)
+	greater := t.apply(op, leftGreater, rightGreater)
+
+	return merge(merge(less, equal), greater)
+}
+
+// survivor returns the element a treap without duplicates keeps of the runs of equal values left
+// and right, either of which may be nil: the first one of left under DuplicatesReject and the last
+// one of right under DuplicatesReplace, falling back to the other run when that one is empty.
+func (t *Treap[T]) survivor(left *Node[T], right *Node[T]) *Node[T] {
+	if t.duplicates == DuplicatesReplace {
+		if right != nil {
+			return right.Rightmost()
+		}
+		return left.Rightmost()
+	}
+	if left != nil {
+		return left.Leftmost()
+	}
+	return right.Leftmost()
+}
+
+// isolate unlinks node from whatever tree it was part of and returns it as a single-element tree
+// with the priority of the first element of a run.
+func (t *Treap[T]) isolate(node *Node[T]) *Node[T] {
+	node.left, node.right, node.parent = nil, nil, nil
+	if t.Canonical() {
+		node.heightPriority = t.priority(node.value, 0)
+	}
+	node.recalc()
+	return node
+}
+
+// setAlgebra applies op to two treaps sharing the same ordering function and consumes both.
+// A nil treap is treated as empty. The result takes the options, including the duplicate
+// policy, of left, or of right when left is nil; an operand allowing duplicates is reduced to
+// that policy first, see policyRoot.
+func setAlgebra[T any](left *Treap[T], right *Treap[T], op *setOperation[T]) *Treap[T] {
+	if left == nil && right == nil {
+		return nil
+	}
+
+	template := left
+	if template == nil {
+		template = right
+	}
+
+	var leftRoot, rightRoot *Node[T]
+	if left != nil {
+		leftRoot, left.root = template.policyRoot(left), nil
+	}
+	if right != nil {
+		rightRoot, right.root = template.policyRoot(right), nil
+	}
+
+	result := template.withRoot(template.apply(op, leftRoot, rightRoot))
+	result.debugCheck()
+
+	return result
+}
+
+// Union returns every value found in either treap.
+// Values present in both contribute occurrences according to mode.
+// The treaps must use equivalent lessFn comparators. Both treaps are consumed.
+func Union[T any](left *Treap[T], right *Treap[T], mode Multiplicity) *Treap[T] {
+	return setAlgebra(left, right, &setOperation[T]{
+		keepOnlyLeft:  true,
+		keepOnlyRight: true,
+		combineEqual:  combineByMultiplicity[T](mode),
+	})
+}
+
+// Intersection returns the values found in both treaps, with occurrences combined according to mode.
+// The treaps must use equivalent lessFn comparators. Both treaps are consumed.
+func Intersection[T any](left *Treap[T], right *Treap[T], mode Multiplicity) *Treap[T] {
+	return setAlgebra(left, right, &setOperation[T]{
+		combineEqual: combineByMultiplicity[T](mode),
+	})
+}
+
+// Difference returns the values of left that are not matched in right.
+// Each occurrence in right cancels one equal occurrence in left.
+// The treaps must use equivalent lessFn comparators. Both treaps are consumed.
+func Difference[T any](left *Treap[T], right *Treap[T]) *Treap[T] {
+	return setAlgebra(left, right, &setOperation[T]{
+		keepOnlyLeft: true,
+		combineEqual: func(leftRun, rightRun *Node[T]) *Node[T] {
+			return takeFirst(leftRun, leftRun.safeSize()-rightRun.safeSize())
+		},
+	})
+}
+
+// SymmetricDifference returns the values found in exactly one of the treaps.
+// Occurrences of a value present in both cancel each other one for one.
+// The treaps must use equivalent lessFn comparators. Both treaps are consumed.
+func SymmetricDifference[T any](left *Treap[T], right *Treap[T]) *Treap[T] {
+	return setAlgebra(left, right, &setOperation[T]{
+		keepOnlyLeft:  true,
+		keepOnlyRight: true,
+		combineEqual: func(leftRun, rightRun *Node[T]) *Node[T] {
+			if leftRun.safeSize() >= rightRun.safeSize() {
+				return takeFirst(leftRun, leftRun.safeSize()-rightRun.safeSize())
+			}
+			return takeFirst(rightRun, rightRun.safeSize()-leftRun.safeSize())
+		},
+	})
+}
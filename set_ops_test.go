@@ -0,0 +1,146 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireHeapAndParents walks the whole treap verifying heap order, sizes and parent pointers.
+func requireHeapAndParents[T any](t *testing.T, tr *Treap[T]) {
+	t.Helper()
+	var walk func(node *Node[T], parent *Node[T])
+	walk = func(node *Node[T], parent *Node[T]) {
+		if node == nil {
+			return
+		}
+		require.Same(t, parent, node.parent)
+		if parent != nil {
+			require.LessOrEqual(t, node.heightPriority, parent.heightPriority)
+		}
+		walk(node.left, node)
+		walk(node.right, node)
+		require.Equal(t, node.left.safeSize()+1+node.right.safeSize(), node.size)
+	}
+	walk(tr.root, nil)
+}
+
+// multisetOp evaluates a set operation on sorted slices using occurrence counts.
+func multisetOp(left []int, right []int, combine func(l, r int) int) []int {
+	counts := map[int][2]int{}
+	for _, v := range left {
+		c := counts[v]
+		c[0]++
+		counts[v] = c
+	}
+	for _, v := range right {
+		c := counts[v]
+		c[1]++
+		counts[v] = c
+	}
+
+	res := []int{}
+	for v, c := range counts {
+		for range combine(c[0], c[1]) {
+			res = append(res, v)
+		}
+	}
+	slices.Sort(res)
+	return res
+}
+
+func TestUnionModes(t *testing.T) {
+	union := func(mode Multiplicity) *Treap[int] {
+		left := NewAutoOrderTreapWithRand(staticRand(), 1, 2, 2, 3, 5)
+		right := NewAutoOrderTreapWithRand(staticRand(), 2, 3, 3, 4)
+		return Union(left, right, mode)
+	}
+
+	requireTreapValues(t, union(MultiplicitySum), 1, 2, 2, 2, 3, 3, 3, 4, 5)
+	requireTreapValues(t, union(MultiplicityMax), 1, 2, 2, 3, 3, 4, 5)
+	requireTreapValues(t, union(MultiplicityMin), 1, 2, 3, 4, 5)
+}
+
+func TestIntersectionModes(t *testing.T) {
+	intersection := func(mode Multiplicity) *Treap[int] {
+		left := NewAutoOrderTreapWithRand(staticRand(), 1, 2, 2, 3, 5)
+		right := NewAutoOrderTreapWithRand(staticRand(), 2, 3, 3, 4)
+		return Intersection(left, right, mode)
+	}
+
+	requireTreapValues(t, intersection(MultiplicityMin), 2, 3)
+	requireTreapValues(t, intersection(MultiplicityMax), 2, 2, 3, 3)
+	requireTreapValues(t, intersection(MultiplicitySum), 2, 2, 2, 3, 3, 3)
+}
+
+func TestDifferenceAndSymmetricDifference(t *testing.T) {
+	left := NewAutoOrderTreapWithRand(staticRand(), 1, 2, 2, 3, 5)
+	right := NewAutoOrderTreapWithRand(staticRand(), 2, 3, 3, 4)
+	requireTreapValues(t, Difference(left, right), 1, 2, 5)
+	require.True(t, left.Empty())
+	require.True(t, right.Empty())
+
+	left = NewAutoOrderTreapWithRand(staticRand(), 1, 2, 2, 3, 5)
+	right = NewAutoOrderTreapWithRand(staticRand(), 2, 3, 3, 4)
+	requireTreapValues(t, SymmetricDifference(left, right), 1, 2, 3, 4, 5)
+}
+
+func TestSetAlgebraWithEmptyAndNil(t *testing.T) {
+	empty := NewAutoOrderTreapWithRand[int](staticRand())
+	full := NewAutoOrderTreapWithRand(staticRand(), 1, 2, 3)
+	requireTreapValues(t, Union(empty, full, MultiplicitySum), 1, 2, 3)
+
+	full = NewAutoOrderTreapWithRand(staticRand(), 1, 2, 3)
+	require.True(t, Intersection(full, nil, MultiplicityMin).Empty())
+
+	full = NewAutoOrderTreapWithRand(staticRand(), 1, 2, 3)
+	requireTreapValues(t, Difference(full, nil), 1, 2, 3)
+
+	full = NewAutoOrderTreapWithRand(staticRand(), 1, 2, 3)
+	require.True(t, Difference(nil, full).Empty())
+
+	require.Nil(t, Union[int](nil, nil, MultiplicitySum))
+	require.Panics(t, func() {
+		Union(NewAutoOrderTreap(1), NewAutoOrderTreap(1), Multiplicity(42))
+	})
+}
+
+func TestSetAlgebraMatchesCounts(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(3, 14))
+
+	type testCase struct {
+		name    string
+		run     func(l, r *Treap[int]) *Treap[int]
+		combine func(l, r int) int
+	}
+	cases := []testCase{
+		{"UnionSum", func(l, r *Treap[int]) *Treap[int] { return Union(l, r, MultiplicitySum) }, func(l, r int) int { return l + r }},
+		{"UnionMax", func(l, r *Treap[int]) *Treap[int] { return Union(l, r, MultiplicityMax) }, func(l, r int) int { return max(l, r) }},
+		{"IntersectionMin", func(l, r *Treap[int]) *Treap[int] { return Intersection(l, r, MultiplicityMin) }, func(l, r int) int { return min(l, r) }},
+		{"Difference", Difference[int], func(l, r int) int { return max(l-r, 0) }},
+		{"SymmetricDifference", SymmetricDifference[int], func(l, r int) int { return max(l-r, r-l) }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for range 50 {
+				left := make([]int, rnd.IntN(60))
+				for i := range left {
+					left[i] = rnd.IntN(40)
+				}
+				right := make([]int, rnd.IntN(60))
+				for i := range right {
+					right[i] = rnd.IntN(40)
+				}
+
+				res := tc.run(NewAutoOrderTreapWithRand(staticRand(), slices.Clone(left)...),
+					NewAutoOrderTreapWithRand(staticRand(), slices.Clone(right)...))
+
+				require.Equal(t, multisetOp(left, right, tc.combine), mustValues(res))
+				requireHeapAndParents(t, res)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package gotreap
+
+// sortedBuilder assembles a tree from nodes arriving in in-order sequence in O(n) total.
+// It keeps the right spine of the tree built so far on a stack: every new node pops the
+// spine nodes with lower priority, adopts the last popped one as its left child and
+// becomes the new bottom of the spine.
+type sortedBuilder[T any] struct {
+	spine []*Node[T]
+}
+
+// append adds node as the new rightmost element.
+func (b *sortedBuilder[T]) append(node *Node[T]) {
+	var popped *Node[T]
+	for len(b.spine) > 0 && b.spine[len(b.spine)-1].heightPriority < node.heightPriority {
+		popped = b.spine[len(b.spine)-1]
+		b.spine = b.spine[:len(b.spine)-1]
+		popped.recalc()
+	}
+
+	node.left = popped
+	popped.safeSetParent(node)
+
+	if len(b.spine) > 0 {
+		top := b.spine[len(b.spine)-1]
+		top.right = node
+		node.parent = top
+	}
+
+	b.spine = append(b.spine, node)
+}
+
+// finish finalizes the remaining spine and returns the root of the built tree.
+func (b *sortedBuilder[T]) finish() *Node[T] {
+	if len(b.spine) == 0 {
+		return nil
+	}
+	for i := len(b.spine) - 1; i >= 0; i-- {
+		b.spine[i].recalc()
+	}
+	root := b.spine[0]
+	b.spine = nil
+	return root
+}
@@ -0,0 +1,360 @@
+package gotreap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// syncMagic opens every reconciliation session.
+var syncMagic = [4]byte{'G', 'T', 'S', 'Y'}
+
+// syncVersion is the current version of the reconciliation protocol.
+const syncVersion = 1
+
+// syncLeafSize is the largest range a replica sends as a list of values instead of a fingerprint.
+const syncLeafSize = 16
+
+// ErrSyncProtocol is returned when the remote replica sends a malformed reconciliation message.
+var ErrSyncProtocol = errors.New("gotreap: sync protocol violation")
+
+// Kinds of entries in a reconciliation message.
+const (
+	// syncFingerprint carries the size and content hash of a range.
+	syncFingerprint byte = iota
+	// syncValues carries the distinct values of a range and asks for the values the receiver has in addition.
+	syncValues
+	// syncValuesFinal carries the values the receiver is missing from a range and expects no answer.
+	syncValuesFinal
+)
+
+// syncBound is one end of a value range; an unbounded end extends to infinity.
+type syncBound[T any] struct {
+	value     T
+	unbounded bool
+}
+
+// syncEntry describes the sender's contents of the value range [lower, upper).
+type syncEntry[T any] struct {
+	kind   byte
+	lower  syncBound[T]
+	upper  syncBound[T]
+	count  int
+	hash   uint64
+	values []T
+}
+
+// SyncStats reports the traffic of one reconciliation session.
+type SyncStats struct {
+	// ValuesSent is the number of values this replica sent.
+	ValuesSent int
+	// ValuesReceived is the number of values the remote replica sent.
+	ValuesReceived int
+	// Inserted is the number of values added to the local treap.
+	Inserted int
+	// Messages is the number of messages this replica sent.
+	Messages int
+}
+
+// Syncer reconciles a treap with a remote replica over a byte stream, so that afterwards both
+// hold the union of their distinct values. The replicas exchange sizes and content hashes of
+// value ranges, recursively splitting the ranges that differ, and only transfer values from
+// small ranges that do not match. Identical stretches cost a single fingerprint each, so the
+// traffic grows with the number of differences rather than the size of the treaps.
+//
+// Both replicas must use the same order, the same Options.ContentHash and compatible codecs.
+// Values are replicated as a set: a value is inserted only when the local treap holds no equal
+// value, and multiplicities of duplicates are not reconciled.
+type Syncer[T any] struct {
+	t     *Treap[T]
+	codec Codec[T]
+	buf   []byte
+}
+
+// NewSyncer returns a syncer reconciling t using codec for individual values.
+// Panics if t has no content hash configured.
+func NewSyncer[T any](t *Treap[T], codec Codec[T]) *Syncer[T] {
+	t.requireContentHash()
+	return &Syncer[T]{t: t, codec: codec}
+}
+
+// Initiate starts a reconciliation session over rw and runs it to completion.
+// The remote replica must call Respond on the other end of the stream.
+// The treap must not be modified by anyone else during the session.
+func (s *Syncer[T]) Initiate(rw io.ReadWriter) (SyncStats, error) {
+	return s.run(rw, true)
+}
+
+// Respond serves a reconciliation session started by Initiate on the other end of rw
+// and runs it to completion. The treap must not be modified by anyone else during the session.
+func (s *Syncer[T]) Respond(rw io.ReadWriter) (SyncStats, error) {
+	return s.run(rw, false)
+}
+
+// run exchanges messages in strict alternation until one side has nothing left to say.
+// An empty message ends the session for both sides.
+func (s *Syncer[T]) run(rw io.ReadWriter, initiator bool) (stats SyncStats, err error) {
+	r := bufio.NewReader(rw)
+	w := bufio.NewWriter(rw)
+
+	if initiator {
+		if _, err := w.Write(append(syncMagic[:], syncVersion)); err != nil {
+			return stats, err
+		}
+		full := s.describe(syncBound[T]{unbounded: true}, syncBound[T]{unbounded: true})
+		if err := s.writeMessage(w, []syncEntry[T]{full}, &stats); err != nil {
+			return stats, err
+		}
+	} else {
+		var header [len(syncMagic) + 1]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return stats, err
+		}
+		if [4]byte(header[:4]) != syncMagic {
+			return stats, ErrInvalidHeader
+		}
+		if header[4] != syncVersion {
+			return stats, fmt.Errorf("%w: unsupported version %d", ErrInvalidHeader, header[4])
+		}
+	}
+
+	for {
+		in, err := s.readMessage(r, &stats)
+		if err != nil {
+			return stats, err
+		}
+		if len(in) == 0 {
+			return stats, nil
+		}
+
+		var out []syncEntry[T]
+		for _, entry := range in {
+			out = s.process(entry, out, &stats)
+		}
+		if err := s.writeMessage(w, out, &stats); err != nil {
+			return stats, err
+		}
+		if len(out) == 0 {
+			return stats, nil
+		}
+	}
+}
+
+// rangeIndices returns the index range [from, to) of the local values within [lower, upper).
+func (s *Syncer[T]) rangeIndices(lower, upper syncBound[T]) (from int, to int) {
+	from, to = 0, s.t.Size()
+	if !lower.unbounded {
+		from = s.t.prefixLen(s.t.condLess(lower.value))
+	}
+	if !upper.unbounded {
+		to = s.t.prefixLen(s.t.condLess(upper.value))
+	}
+	return from, max(from, to)
+}
+
+// distinctValues returns the local values with indices in [from, to), skipping duplicates.
+func (s *Syncer[T]) distinctValues(from, to int) []T {
+	values := make([]T, 0, to-from)
+	for _, value := range s.t.ValuesBetweenIndices(from, to) {
+		if len(values) == 0 || s.t.lessFn(values[len(values)-1], value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// rangeDigest returns the mixed, length-aware content hash of the local values with indices in [from, to).
+func (s *Syncer[T]) rangeDigest(from, to int) uint64 {
+	return s.t.root.hashIndexRange(from, to, 0).digest()
+}
+
+// describe summarizes the local contents of [lower, upper): small ranges are sent as values,
+// larger ones as a fingerprint.
+func (s *Syncer[T]) describe(lower, upper syncBound[T]) syncEntry[T] {
+	from, to := s.rangeIndices(lower, upper)
+	if to-from <= syncLeafSize {
+		return syncEntry[T]{kind: syncValues, lower: lower, upper: upper, values: s.distinctValues(from, to)}
+	}
+	return syncEntry[T]{kind: syncFingerprint, lower: lower, upper: upper, count: to - from, hash: s.rangeDigest(from, to)}
+}
+
+// process handles one entry received from the remote replica and appends the answer to out.
+func (s *Syncer[T]) process(entry syncEntry[T], out []syncEntry[T], stats *SyncStats) []syncEntry[T] {
+	from, to := s.rangeIndices(entry.lower, entry.upper)
+
+	switch entry.kind {
+	case syncFingerprint:
+		if to-from == entry.count && s.rangeDigest(from, to) == entry.hash {
+			return out
+		}
+		if to-from <= syncLeafSize {
+			return append(out, syncEntry[T]{kind: syncValues, lower: entry.lower, upper: entry.upper, values: s.distinctValues(from, to)})
+		}
+
+		// Split at the local median, moved past any run of values equal to the first one
+		// so that both halves are non-empty locally.
+		pivot := s.t.At(from + (to-from)/2).value
+		first := s.t.At(from).value
+		if !s.t.lessFn(first, pivot) {
+			next := s.t.prefixLen(s.t.condLeq(first))
+			if next >= to {
+				return append(out, syncEntry[T]{kind: syncValues, lower: entry.lower, upper: entry.upper, values: []T{first}})
+			}
+			pivot = s.t.At(next).value
+		}
+		mid := syncBound[T]{value: pivot}
+		return append(out, s.describe(entry.lower, mid), s.describe(mid, entry.upper))
+
+	default:
+		local := s.distinctValues(from, to)
+		var missingRemotely []T
+		i := 0
+		for _, value := range entry.values {
+			for i < len(local) && s.t.lessFn(local[i], value) {
+				missingRemotely = append(missingRemotely, local[i])
+				i++
+			}
+			if i < len(local) && !s.t.lessFn(value, local[i]) {
+				i++
+				continue
+			}
+			s.t.InsertRight(value)
+			stats.Inserted++
+		}
+		missingRemotely = append(missingRemotely, local[i:]...)
+
+		if entry.kind == syncValuesFinal || len(missingRemotely) == 0 {
+			return out
+		}
+		return append(out, syncEntry[T]{kind: syncValuesFinal, lower: entry.lower, upper: entry.upper, values: missingRemotely})
+	}
+}
+
+// writeMessage writes entries as one message and flushes it to the stream.
+func (s *Syncer[T]) writeMessage(w *bufio.Writer, entries []syncEntry[T], stats *SyncStats) error {
+	s.buf = binary.AppendUvarint(s.buf[:0], uint64(len(entries)))
+	for _, entry := range entries {
+		s.buf = append(s.buf, entry.kind)
+		s.buf = s.appendBound(s.buf, entry.lower)
+		s.buf = s.appendBound(s.buf, entry.upper)
+		if entry.kind == syncFingerprint {
+			s.buf = binary.AppendUvarint(s.buf, uint64(entry.count))
+			s.buf = binary.BigEndian.AppendUint64(s.buf, entry.hash)
+			continue
+		}
+		s.buf = binary.AppendUvarint(s.buf, uint64(len(entry.values)))
+		for _, value := range entry.values {
+			s.buf = s.codec.Append(s.buf, value)
+		}
+		stats.ValuesSent += len(entry.values)
+	}
+
+	if _, err := w.Write(s.buf); err != nil {
+		return err
+	}
+	stats.Messages++
+	return w.Flush()
+}
+
+func (s *Syncer[T]) appendBound(dst []byte, bound syncBound[T]) []byte {
+	if bound.unbounded {
+		return append(dst, 0)
+	}
+	return s.codec.Append(append(dst, 1), bound.value)
+}
+
+// readMessage reads one message and checks that its ranges and values are well-formed.
+func (s *Syncer[T]) readMessage(r *bufio.Reader, stats *SyncStats) ([]syncEntry[T], error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	var entries []syncEntry[T]
+	for range n {
+		var entry syncEntry[T]
+		if entry.kind, err = r.ReadByte(); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if entry.kind > syncValuesFinal {
+			return nil, fmt.Errorf("%w: unknown entry kind %d", ErrSyncProtocol, entry.kind)
+		}
+		if entry.lower, err = s.readBound(r); err != nil {
+			return nil, err
+		}
+		if entry.upper, err = s.readBound(r); err != nil {
+			return nil, err
+		}
+		if !entry.lower.unbounded && !entry.upper.unbounded && !s.t.lessFn(entry.lower.value, entry.upper.value) {
+			return nil, fmt.Errorf("%w: empty range", ErrSyncProtocol)
+		}
+
+		if entry.kind == syncFingerprint {
+			count, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			var hash [8]byte
+			if _, err := io.ReadFull(r, hash[:]); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			entry.count, entry.hash = int(count), binary.BigEndian.Uint64(hash[:])
+		} else if entry.values, err = s.readValues(r, entry.lower, entry.upper); err != nil {
+			return nil, err
+		}
+
+		stats.ValuesReceived += len(entry.values)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *Syncer[T]) readBound(r *bufio.Reader) (syncBound[T], error) {
+	flag, err := r.ReadByte()
+	if err != nil {
+		return syncBound[T]{}, unexpectedEOF(err)
+	}
+	switch flag {
+	case 0:
+		return syncBound[T]{unbounded: true}, nil
+	case 1:
+		value, err := s.codec.Read(r)
+		return syncBound[T]{value: value}, unexpectedEOF(err)
+	default:
+		return syncBound[T]{}, fmt.Errorf("%w: invalid bound flag %d", ErrSyncProtocol, flag)
+	}
+}
+
+// readValues reads a list of values, which must be strictly ascending and within [lower, upper).
+func (s *Syncer[T]) readValues(r *bufio.Reader, lower, upper syncBound[T]) ([]T, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+
+	var values []T
+	for i := range n {
+		value, err := s.codec.Read(r)
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if i > 0 && !s.t.lessFn(values[len(values)-1], value) {
+			return nil, fmt.Errorf("%w: values are not strictly ascending", ErrSyncProtocol)
+		}
+		if (!lower.unbounded && s.t.lessFn(value, lower.value)) || (!upper.unbounded && !s.t.lessFn(value, upper.value)) {
+			return nil, fmt.Errorf("%w: value outside of its range", ErrSyncProtocol)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// unexpectedEOF reports a stream ending in the middle of a message as io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,186 @@
+package gotreap
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// syncPair reconciles a and b over net.Pipe and returns the stats of both sides.
+func syncPair[T any](t *testing.T, a, b *Treap[T], codec Codec[T]) (SyncStats, SyncStats) {
+	t.Helper()
+	left, right := net.Pipe()
+	defer left.Close()
+	defer right.Close()
+
+	type result struct {
+		stats SyncStats
+		err   error
+	}
+	done := make(chan result)
+	go func() {
+		stats, err := NewSyncer(b, codec).Respond(right)
+		done <- result{stats, err}
+	}()
+
+	initiatorStats, err := NewSyncer(a, codec).Initiate(left)
+	require.NoError(t, err)
+	responder := <-done
+	require.NoError(t, responder.err)
+	return initiatorStats, responder.stats
+}
+
+func TestSyncConvergesToUnion(t *testing.T) {
+	a := newHashed(1, 2, 3, 5, 8)
+	b := newHashed(2, 3, 4, 5, 9, 10)
+	syncPair(t, a, b, IntCodec[int]{})
+	requireTreapValues(t, a, 1, 2, 3, 4, 5, 8, 9, 10)
+	requireTreapValues(t, b, 1, 2, 3, 4, 5, 8, 9, 10)
+
+	empty := newHashed()
+	syncPair(t, empty, b, IntCodec[int]{})
+	require.True(t, empty.Equal(b))
+
+	other := newHashed()
+	syncPair(t, b, other, IntCodec[int]{})
+	require.True(t, other.Equal(b))
+	require.NoError(t, other.Validate())
+}
+
+func TestSyncTransfersOnlyDifferences(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(7, 8))
+	var shared []int
+	for v := range 20000 {
+		shared = append(shared, v*2)
+	}
+	a := newHashed(shared...)
+	b := newHashed(shared...)
+
+	sa, sb := syncPair(t, a, b, IntCodec[int]{})
+	require.Zero(t, sa.ValuesSent+sb.ValuesSent)
+	require.Equal(t, 1, sa.Messages)
+	require.Equal(t, 1, sb.Messages)
+
+	for range 5 {
+		a.InsertRight(rnd.IntN(40000)*2 + 1)
+		b.InsertRight(rnd.IntN(40000)*2 + 1)
+	}
+	sa, sb = syncPair(t, a, b, IntCodec[int]{})
+	require.True(t, a.Equal(b))
+	require.Equal(t, 20010, a.Size())
+	require.Equal(t, 5, sa.Inserted)
+	require.Equal(t, 5, sb.Inserted)
+	require.Less(t, sa.ValuesSent+sb.ValuesSent, 20*syncLeafSize)
+	require.Equal(t, sa.ValuesSent, sb.ValuesReceived)
+	require.Equal(t, sb.ValuesSent, sa.ValuesReceived)
+}
+
+func TestSyncRandomReplicas(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(9, 10))
+	for range 20 {
+		var left, right, union []int
+		for v := range 1000 {
+			switch rnd.IntN(10) {
+			case 0:
+				left = append(left, v)
+			case 1:
+				right = append(right, v)
+			case 2:
+				continue
+			default:
+				left = append(left, v)
+				right = append(right, v)
+			}
+			union = append(union, v)
+		}
+
+		a, b := newHashed(left...), newHashed(right...)
+		syncPair(t, a, b, IntCodec[int]{})
+		require.Equal(t, union, mustValues(a))
+		require.Equal(t, union, mustValues(b))
+	}
+}
+
+func TestSyncDuplicatesAndStrings(t *testing.T) {
+	a := newHashed(1, 1, 1, 2)
+	b := newHashed(1, 3)
+	syncPair(t, a, b, IntCodec[int]{})
+	requireTreapValues(t, a, 1, 1, 1, 2, 3)
+	requireTreapValues(t, b, 1, 2, 3)
+
+	var many []int
+	for range 100 {
+		many = append(many, 7)
+	}
+	c, d := newHashed(many...), newHashed(7, 8)
+	syncPair(t, c, d, IntCodec[int]{})
+	require.Equal(t, 101, c.Size())
+	requireTreapValues(t, d, 7, 8)
+
+	opts := Options[string]{ContentHash: CodecHash[string](StringCodec[string]{}, 0)}
+	x := NewTreapCmpWithOptions(cmp.Compare[string], opts, "apple", "pear")
+	y := NewTreapCmpWithOptions(cmp.Compare[string], opts, "fig", "pear", "plum")
+	syncPair(t, x, y, StringCodec[string]{})
+	requireTreapValues(t, x, "apple", "fig", "pear", "plum")
+	require.True(t, x.Equal(y))
+}
+
+func TestSyncWithWeakValueHash(t *testing.T) {
+	opts := Options[int]{ContentHash: func(v int) uint64 { return uint64(v) }}
+	rnd := rand.New(rand.NewPCG(17, 18))
+	var left, right []int
+	for v := range 2000 {
+		if rnd.IntN(50) != 0 {
+			left = append(left, v)
+		}
+		if rnd.IntN(50) != 0 {
+			right = append(right, v)
+		}
+	}
+
+	a := NewTreapCmpWithOptions(cmp.Compare[int], opts, left...)
+	b := NewTreapCmpWithOptions(cmp.Compare[int], opts, right...)
+	syncPair(t, a, b, IntCodec[int]{})
+	require.Equal(t, mustValues(a), mustValues(b))
+	require.True(t, a.Equal(b))
+}
+
+func TestSyncErrors(t *testing.T) {
+	require.Panics(t, func() { NewSyncer(NewAutoOrderTreap(1), IntCodec[int]{}) })
+
+	var out bytes.Buffer
+	_, err := NewSyncer(newHashed(), IntCodec[int]{}).Respond(&struct {
+		io.Reader
+		io.Writer
+	}{bytes.NewReader([]byte("nope!")), &out})
+	require.ErrorIs(t, err, ErrInvalidHeader)
+
+	respond := func(input []byte) error {
+		_, err := NewSyncer(newHashed(1, 2, 3), IntCodec[int]{}).Respond(&struct {
+			io.Reader
+			io.Writer
+		}{bytes.NewReader(input), io.Discard})
+		return err
+	}
+	header := append(syncMagic[:], syncVersion)
+
+	// One values entry over (-inf, +inf) holding 5 and 4, which are out of order.
+	unsorted := append(bytes.Clone(header), 1, syncValues, 0, 0, 2, 10, 8)
+	require.ErrorIs(t, respond(unsorted), ErrSyncProtocol)
+
+	// A values entry over [0, 2) holding 3.
+	outside := append(bytes.Clone(header), 1, syncValues, 1, 0, 1, 4, 1, 6)
+	require.ErrorIs(t, respond(outside), ErrSyncProtocol)
+
+	unknownKind := append(bytes.Clone(header), 1, 9)
+	require.ErrorIs(t, respond(unknownKind), ErrSyncProtocol)
+
+	truncated := append(bytes.Clone(header), 1, syncFingerprint, 0, 0, 3)
+	require.True(t, errors.Is(respond(truncated), io.ErrUnexpectedEOF))
+}
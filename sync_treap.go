@@ -0,0 +1,282 @@
+package gotreap
+
+import (
+	"iter"
+	"sync"
+)
+
+// SyncTreap is a Treap guarded by a reader/writer lock, safe for concurrent use.
+// Read-only methods share the lock, mutating ones hold it exclusively.
+// Methods return values rather than nodes, since nodes must not be touched outside the lock.
+type SyncTreap[T any] struct {
+	mu    sync.RWMutex
+	treap *Treap[T]
+}
+
+// NewSyncTreap wraps t for concurrent use. The caller must not use t directly afterwards.
+func NewSyncTreap[T any](t *Treap[T]) *SyncTreap[T] {
+	if t == nil {
+		panic("treap must not be nil")
+	}
+	return &SyncTreap[T]{treap: t}
+}
+
+// View runs fn with shared access to the underlying treap.
+// fn must only read, must not retain the treap or its nodes, and must not call methods of s.
+func (s *SyncTreap[T]) View(fn func(t *Treap[T])) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.treap)
+}
+
+// Update runs fn with exclusive access to the underlying treap, so several operations apply atomically.
+// fn must not retain the treap or its nodes, and must not call methods of s.
+func (s *SyncTreap[T]) Update(fn func(t *Treap[T])) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.treap)
+}
+
+// InsertLeft inserts value before any equal elements and returns its index.
+func (s *SyncTreap[T]) InsertLeft(value T) (index int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.InsertLeft(value)
+}
+
+// InsertRight inserts value after any equal elements and returns its index.
+func (s *SyncTreap[T]) InsertRight(value T) (index int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.InsertRight(value)
+}
+
+// Insert adds value according to the duplicate policy, see Treap.Insert.
+func (s *SyncTreap[T]) Insert(value T) (index int, inserted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.Insert(value)
+}
+
+// ReplaceOrInsert overwrites an equal value or inserts value, see Treap.ReplaceOrInsert.
+func (s *SyncTreap[T]) ReplaceOrInsert(value T) (old T, replaced bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.ReplaceOrInsert(value)
+}
+
+// GetOrInsert returns the value equal to value, inserting value first if there is none.
+// inserted reports whether value was added, see Treap.GetOrInsert.
+func (s *SyncTreap[T]) GetOrInsert(value T) (stored T, inserted bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	node, inserted := s.treap.GetOrInsert(value)
+	return node.value, inserted
+}
+
+// EraseAll removes every occurrence of value and reports how many were deleted.
+func (s *SyncTreap[T]) EraseAll(value T) (erasedCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.EraseAll(value)
+}
+
+// EraseLeftmost removes up to n matching values starting from the leftmost occurrence.
+func (s *SyncTreap[T]) EraseLeftmost(value T, n int) (erasedCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.EraseLeftmost(value, n)
+}
+
+// EraseRightmost removes up to n matching values starting from the rightmost occurrence.
+func (s *SyncTreap[T]) EraseRightmost(value T, n int) (erasedCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.EraseRightmost(value, n)
+}
+
+// EraseRange removes values between startValue and endValue, see Treap.EraseRange.
+func (s *SyncTreap[T]) EraseRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) (erasedCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.EraseRange(startValue, inclusiveStart, endValue, inclusiveEnd)
+}
+
+// EraseAt removes up to count elements starting at index, see Treap.EraseAt.
+func (s *SyncTreap[T]) EraseAt(index int, count int) (erasedCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.EraseAt(index, count)
+}
+
+// PopLeftmost removes and returns the minimum value, reporting success.
+func (s *SyncTreap[T]) PopLeftmost() (value T, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.PopLeftmost()
+}
+
+// PopRightmost removes and returns the maximum value, reporting success.
+func (s *SyncTreap[T]) PopRightmost() (value T, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.treap.PopRightmost()
+}
+
+// Clear removes all elements.
+func (s *SyncTreap[T]) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.treap.Clear()
+}
+
+// At returns the value located at index, reporting false if it is out of range.
+// Supports negative indexing where -1 refers to the last element.
+func (s *SyncTreap[T]) At(index int) (value T, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	node := s.treap.At(index)
+	return node.Value(), node.Valid()
+}
+
+// FindLowerBound returns the first value not less than value along with its index.
+// The ok flag is false if there is no such value.
+func (s *SyncTreap[T]) FindLowerBound(value T) (result T, index int, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	node, index := s.treap.FindLowerBound(value)
+	return node.Value(), index, node.Valid()
+}
+
+// FindUpperBound returns the last value not greater than value along with its index.
+// The ok flag is false if there is no such value.
+func (s *SyncTreap[T]) FindUpperBound(value T) (result T, index int, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	node, index := s.treap.FindUpperBound(value)
+	return node.Value(), index, node.Valid()
+}
+
+// Leftmost returns the minimum value, reporting false if the treap is empty.
+func (s *SyncTreap[T]) Leftmost() (value T, ok bool) {
+	return s.At(0)
+}
+
+// Rightmost returns the maximum value, reporting false if the treap is empty.
+func (s *SyncTreap[T]) Rightmost() (value T, ok bool) {
+	return s.At(-1)
+}
+
+// Count reports the number of occurrences of value.
+func (s *SyncTreap[T]) Count(value T) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.treap.Count(value)
+}
+
+// CountRange returns how many values fall between startValue and endValue, see Treap.CountRange.
+func (s *SyncTreap[T]) CountRange(startValue T, inclusiveStart bool, endValue T, inclusiveEnd bool) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.treap.CountRange(startValue, inclusiveStart, endValue, inclusiveEnd)
+}
+
+// Size reports the number of elements.
+func (s *SyncTreap[T]) Size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.treap.Size()
+}
+
+// Empty reports whether the treap contains no elements.
+func (s *SyncTreap[T]) Empty() bool {
+	return s.Size() == 0
+}
+
+// Snapshot returns a copy of all values in order, taken atomically under the read lock.
+func (s *SyncTreap[T]) Snapshot() []T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	values := make([]T, 0, s.treap.Size())
+	for value := range s.treap.Values() {
+		values = append(values, value)
+	}
+	return values
+}
+
+// Iterate over values (leftmost to rightmost) with snapshot semantics:
+// the values are copied atomically when iteration starts and the lock is released
+// before the first yield, so the loop body may freely modify s.
+// Costs O(n) extra memory.
+func (s *SyncTreap[T]) Values() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, value := range s.Snapshot() {
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// Iterate over values in reverse order (rightmost to leftmost) with snapshot semantics, see Values.
+func (s *SyncTreap[T]) ValuesBackwards() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		values := s.Snapshot()
+		for i := len(values) - 1; i >= 0; i-- {
+			if !yield(values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Iterate over values (leftmost to rightmost) with live semantics:
+// every step briefly takes the read lock to find the element following the last one yielded,
+// so changes made concurrently or by the loop body ahead of the cursor are observed,
+// while changes behind it are not. No lock is held while yielding and each step is O(log n).
+func (s *SyncTreap[T]) ValuesLive() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var last T
+		// seen counts how many values equal to last have been yielded, to step over duplicates.
+		seen := 0
+		for {
+			value, ok := s.liveNext(last, seen)
+			if !ok {
+				return
+			}
+			if seen > 0 && !s.treap.lessFn(last, value) {
+				seen++
+			} else {
+				seen = 1
+			}
+			last = value
+			if !yield(value) {
+				return
+			}
+		}
+	}
+}
+
+// liveNext returns the element following the seen-th occurrence of last,
+// or the first element when seen is zero.
+func (s *SyncTreap[T]) liveNext(last T, seen int) (value T, ok bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if seen == 0 {
+		node := s.treap.At(0)
+		return node.Value(), node.Valid()
+	}
+
+	lowerBound, first := s.treap.root.lookupLeftmostUnmatch(s.treap.condLess(last), 0)
+	if lowerBound == nil {
+		// Everything left is less than last, so the iteration is over.
+		return value, false
+	}
+	if node := s.treap.At(first + seen); node != nil && !s.treap.lessFn(last, node.value) {
+		return node.value, true
+	}
+
+	node, _ := s.treap.root.lookupLeftmostUnmatch(s.treap.condLeq(last), 0)
+	return node.Value(), node.Valid()
+}
@@ -0,0 +1,151 @@
+package gotreap
+
+import (
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncTreapDelegates(t *testing.T) {
+	s := NewSyncTreap(NewAutoOrderTreapWithRand(staticRand(), 5, 1, 3, 3))
+
+	require.Equal(t, 4, s.Size())
+	require.Equal(t, 2, s.Count(3))
+	require.Equal(t, 0, s.InsertLeft(0))
+	require.Equal(t, 5, s.InsertRight(7))
+
+	v, ok := s.At(-1)
+	require.True(t, ok)
+	require.Equal(t, 7, v)
+	_, ok = s.At(10)
+	require.False(t, ok)
+
+	v, idx, ok := s.FindLowerBound(2)
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+	require.Equal(t, 2, idx)
+	_, _, ok = s.FindUpperBound(-1)
+	require.False(t, ok)
+
+	require.Equal(t, 3, s.CountRange(1, true, 5, false))
+	require.Equal(t, 2, s.EraseAll(3))
+
+	v, ok = s.PopLeftmost()
+	require.True(t, ok)
+	require.Equal(t, 0, v)
+	v, ok = s.Rightmost()
+	require.True(t, ok)
+	require.Equal(t, 7, v)
+
+	require.Equal(t, []int{1, 5, 7}, s.Snapshot())
+	s.Clear()
+	require.True(t, s.Empty())
+	_, ok = s.Leftmost()
+	require.False(t, ok)
+}
+
+func TestSyncTreapViewAndUpdate(t *testing.T) {
+	s := NewSyncTreap(NewAutoOrderTreapWithRand[int](staticRand()))
+
+	s.Update(func(tr *Treap[int]) {
+		for i := range 5 {
+			tr.InsertRight(i)
+		}
+		tr.EraseAt(0, 2)
+	})
+
+	var values []int
+	s.View(func(tr *Treap[int]) {
+		values = mustValues(tr)
+	})
+	require.Equal(t, []int{2, 3, 4}, values)
+}
+
+func TestSyncTreapSnapshotIterators(t *testing.T) {
+	s := NewSyncTreap(NewAutoOrderTreapWithRand(staticRand(), 1, 2, 3))
+
+	// The loop body may write, and the snapshot must not observe it.
+	var seen []int
+	for v := range s.Values() {
+		s.InsertRight(v + 10)
+		seen = append(seen, v)
+	}
+	require.Equal(t, []int{1, 2, 3}, seen)
+	require.Equal(t, []int{1, 2, 3, 11, 12, 13}, s.Snapshot())
+
+	require.Equal(t, []int{13, 12, 11, 3, 2, 1}, slices.Collect(s.ValuesBackwards()))
+}
+
+func TestSyncTreapLiveIterator(t *testing.T) {
+	s := NewSyncTreap(NewAutoOrderTreapWithRand(staticRand(), 1, 2, 2, 2, 4))
+
+	require.Equal(t, []int{1, 2, 2, 2, 4}, slices.Collect(s.ValuesLive()))
+
+	// Insertions ahead of the cursor are observed, erasures behind it are not.
+	var seen []int
+	for v := range s.ValuesLive() {
+		if v == 2 && len(seen) == 1 {
+			s.InsertRight(3)
+			s.EraseAll(1)
+		}
+		seen = append(seen, v)
+	}
+	require.Equal(t, []int{1, 2, 2, 2, 3, 4}, seen)
+
+	var stopped []int
+	for v := range s.ValuesLive() {
+		stopped = append(stopped, v)
+		if len(stopped) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []int{2, 2}, stopped)
+
+	// Erasing the value just yielded, and with it everything not less than it, ends the iteration.
+	tail := NewSyncTreap(NewAutoOrderTreapWithRand(staticRand(), 1, 2, 3))
+	var rest []int
+	for v := range tail.ValuesLive() {
+		rest = append(rest, v)
+		if v == 3 {
+			tail.EraseAll(3)
+		}
+	}
+	require.Equal(t, []int{1, 2, 3}, rest)
+}
+
+func TestSyncTreapConcurrentAccess(t *testing.T) {
+	s := NewSyncTreap(NewAutoOrderTreapWithRand[int](staticRand()))
+
+	const writers, perWriter = 4, 250
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				s.InsertRight(w*perWriter + i)
+			}
+		}()
+	}
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				require.True(t, slices.IsSorted(s.Snapshot()))
+				require.True(t, slices.IsSorted(slices.Collect(s.ValuesLive())))
+				s.View(func(tr *Treap[int]) {
+					require.Equal(t, tr.Size(), len(mustValues(tr)))
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, writers*perWriter, s.Size())
+	s.View(func(tr *Treap[int]) {
+		requireHeapAndParents(t, tr)
+	})
+}
@@ -4,23 +4,59 @@ import (
 	"cmp"
 	"iter"
 	"math/rand/v2"
+	"slices"
 	"sort"
 )
 
 type Treap[T any] struct {
 	lessFn func(a T, b T) bool
+	// cmpFn is the three-way comparator the treap was built with, or nil for lessFn-only treaps.
+	cmpFn  func(a T, b T) int
 	randFn func() int
-	root   *Node[T]
+	// priorityHash, when set, replaces randFn as the source of heightPriority, see Options.PriorityHash.
+	priorityHash func(value T) uint64
+	// duplicates decides what inserting a value equal to a stored one does, see Options.Duplicates.
+	duplicates DuplicatePolicy
+	traits     *nodeTraits[T]
+	root       *Node[T]
+}
+
+// Options configures optional treap features. Zero fields keep the defaults.
+type Options[T any] struct {
+	// RandFn supplies heap priorities for tree balancing. Defaults to rand.Int.
+	RandFn func() int
+	// Aggregate is an associative function maintained over every subtree,
+	// enabling O(log n) Aggregate, AggregateRange and AggregateIndexRange queries.
+	Aggregate func(a T, b T) T
+	// PriorityHash, when set, derives every heightPriority from a hash of the value instead of RandFn.
+	// The shape of the tree then depends only on its contents: treaps holding the same values in
+	// the same order are structurally identical, however they were built. Equal values are told
+	// apart by their rank within their run, which is mixed into the hash. To keep ranks stable,
+	// InsertLeft and InsertRight both add a value after any equal elements, while edits that move
+	// other elements of a run, such as EraseLeftmost, EraseNode or Cut inside a run, re-derive
+	// the priorities of that run in O(k) for a run of k elements. See CodecHash for a hash
+	// that is stable across processes.
+	PriorityHash func(value T) uint64
+	// ContentHash hashes single values. When set, every subtree caches a hash of its in-order
+	// contents, enabling Hash, Equal and Diff. It may be the same function as PriorityHash.
+	ContentHash func(value T) uint64
+	// Duplicates selects what inserting a value equal to a stored one does. The default,
+	// DuplicatesAllow, keeps every copy; the other policies make the treap a set. Insertions,
+	// the constructors, decoding and FromSorted apply the policy to every value. Merge and the
+	// set operations keep one element of a value found in both operands, the left one under
+	// DuplicatesReject and the right one under DuplicatesReplace, and InsertAfterNode,
+	// InsertBeforeNode and ReplaceValue panic on a value equal to another element.
+	Duplicates DuplicatePolicy
 }
 
 // NewAutoOrderTreap builds an ordered treap using the natural ordering for type T.
 func NewAutoOrderTreap[T cmp.Ordered](values ...T) *Treap[T] {
-	return NewTreap(cmp.Less[T], values...)
+	return NewTreapCmp(cmp.Compare[T], values...)
 }
 
 // NewAutoOrderTreapWithRand builds an ordered treap using the natural ordering for type T and a custom random function.
 func NewAutoOrderTreapWithRand[T cmp.Ordered](randFn func() int, values ...T) *Treap[T] {
-	return NewTreapWithRand(cmp.Less[T], randFn, values...)
+	return NewTreapCmpWithRand(cmp.Compare[T], randFn, values...)
 }
 
 // NewTreap constructs a treap using lessFn for ordering and optionally inserts values.
@@ -36,24 +72,165 @@ func NewTreapWithRand[T any](lessFn func(a T, b T) bool, randFn func() int, valu
 	if randFn == nil {
 		panic("randFn must not be nil")
 	}
+	return NewTreapWithOptions(lessFn, Options[T]{RandFn: randFn}, values...)
+}
+
+// NewTreapWithOptions constructs a treap using lessFn for ordering and the features enabled in opts, and optionally inserts values.
+func NewTreapWithOptions[T any](lessFn func(a T, b T) bool, opts Options[T], values ...T) *Treap[T] {
+	if lessFn == nil {
+		panic("lessFn must not be nil")
+	}
+
+	randFn := opts.RandFn
+	if randFn == nil {
+		randFn = rand.Int
+	}
+
+	var traits *nodeTraits[T]
+	if opts.Aggregate != nil || opts.ContentHash != nil {
+		traits = &nodeTraits[T]{aggregate: opts.Aggregate, hash: opts.ContentHash}
+	}
 
 	t := &Treap[T]{
-		lessFn: lessFn,
-		randFn: randFn,
-		root:   nil,
+		lessFn:       lessFn,
+		randFn:       randFn,
+		priorityHash: opts.PriorityHash,
+		duplicates:   opts.Duplicates,
+		traits:       traits,
+		root:         nil,
 	}
 
-	sort.Slice(values, func(i, j int) bool {
-		return lessFn(values[i], values[j])
-	})
+	t.root = t.build(values)
+	t.debugCheck()
 
-	for _, val := range values {
-		t.root = merge(t.root, newNode(val, randFn()))
+	return t
+}
+
+// NewTreapCmp constructs a treap ordered by the three-way comparator cmpFn, which returns
+// a negative number, zero or a positive number when a is less than, equal to or greater than b,
+// as cmp.Compare and slices.SortFunc do. Lookups of exact values (Count, EraseAll and alike)
+// need about half the comparisons of a treap built from a lessFn.
+func NewTreapCmp[T any](cmpFn func(a T, b T) int, values ...T) *Treap[T] {
+	return NewTreapCmpWithOptions(cmpFn, Options[T]{}, values...)
+}
+
+// NewTreapCmpWithRand constructs a treap ordered by cmpFn using randFn for tree balancing, see NewTreapCmp.
+func NewTreapCmpWithRand[T any](cmpFn func(a T, b T) int, randFn func() int, values ...T) *Treap[T] {
+	if randFn == nil {
+		panic("randFn must not be nil")
+	}
+	return NewTreapCmpWithOptions(cmpFn, Options[T]{RandFn: randFn}, values...)
+}
+
+// NewTreapCmpWithOptions constructs a treap ordered by cmpFn with the features enabled in opts, see NewTreapCmp.
+func NewTreapCmpWithOptions[T any](cmpFn func(a T, b T) int, opts Options[T], values ...T) *Treap[T] {
+	if cmpFn == nil {
+		panic("cmpFn must not be nil")
 	}
 
+	t := NewTreapWithOptions(func(a T, b T) bool { return cmpFn(a, b) < 0 }, opts)
+	t.cmpFn = cmpFn
+	t.root = t.build(values)
+	t.debugCheck()
+
 	return t
 }
 
+// withRoot creates a treap sharing t's configuration with the provided root.
+func (t *Treap[T]) withRoot(root *Node[T]) *Treap[T] {
+	return &Treap[T]{
+		lessFn:       t.lessFn,
+		cmpFn:        t.cmpFn,
+		randFn:       t.randFn,
+		priorityHash: t.priorityHash,
+		duplicates:   t.duplicates,
+		traits:       t.traits,
+		root:         root,
+	}
+}
+
+// equalRange returns the index range [from, to) of the elements equal to value.
+// With a three-way comparator a single descent is enough, see equalRangeFunc;
+// otherwise it runs two independent searches.
+func (t *Treap[T]) equalRange(value T) (from int, to int) {
+	if t.cmpFn == nil {
+		return t.prefixLen(t.condLess(value)), t.prefixLen(t.condLeq(value))
+	}
+	return equalRangeFunc(t.root, func(nodeValue T) int { return t.cmpFn(nodeValue, value) })
+}
+
+// equalRangeFunc returns the index range [from, to) of the elements for which compare reports zero,
+// where compare orders node values relative to a fixed target consistently with the tree order.
+// A single descent finds the topmost matching node and the two bounds are then searched only below it.
+func equalRangeFunc[T any](root *Node[T], compare func(nodeValue T) int) (from int, to int) {
+	offset := 0
+	for cur := root; cur != nil; {
+		c := compare(cur.value)
+		switch {
+		case c < 0:
+			offset += cur.left.safeSize() + 1
+			cur = cur.right
+		case c > 0:
+			cur = cur.left
+		default:
+			from = offset + countLeading(cur.left, func(v T) bool { return compare(v) < 0 })
+			to = offset + cur.left.safeSize() + 1 + countLeading(cur.right, func(v T) bool { return compare(v) <= 0 })
+			return from, to
+		}
+	}
+	return offset, offset
+}
+
+// countLeading returns how many leading elements of the subtree rooted at node satisfy the monotone pred.
+func countLeading[T any](node *Node[T], pred func(value T) bool) int {
+	count := 0
+	for cur := node; cur != nil; {
+		if pred(cur.value) {
+			count += cur.left.safeSize() + 1
+			cur = cur.right
+		} else {
+			cur = cur.left
+		}
+	}
+	return count
+}
+
+// build sorts values in place and returns the root of a new tree holding them.
+// Unless duplicates are allowed, equal values are resolved in input order, see uniqueBuilder.
+func (t *Treap[T]) build(values []T) *Node[T] {
+	switch {
+	case t.duplicates != DuplicatesAllow && t.cmpFn != nil:
+		slices.SortStableFunc(values, t.cmpFn)
+	case t.duplicates != DuplicatesAllow:
+		sort.SliceStable(values, func(i, j int) bool {
+			return t.lessFn(values[i], values[j])
+		})
+	case t.cmpFn != nil:
+		slices.SortFunc(values, t.cmpFn)
+	default:
+		sort.Slice(values, func(i, j int) bool {
+			return t.lessFn(values[i], values[j])
+		})
+	}
+
+	builder := t.newUniqueBuilder()
+	for _, val := range values {
+		builder.append(val)
+	}
+	return builder.finish()
+}
+
+// newNode creates a detached node for value that shares the treap's traits.
+// rank is the position of value within its run of equal values, see priority.
+func (t *Treap[T]) newNode(value T, rank int) *Node[T] {
+	if t.traits == nil {
+		return newNode(value, t.priority(value, rank))
+	}
+	node := newExtNode(value, t.priority(value, rank), t.traits)
+	node.recalc()
+	return node
+}
+
 // condLess returns a predicate that is true for nodes whose value is less than value.
 func (t *Treap[T]) condLess(value T) leftCondition[T] {
 	return func(nodeValue T, nodeIndex int) bool {
@@ -69,78 +246,97 @@ func (t *Treap[T]) condLeq(value T) leftCondition[T] {
 }
 
 // condCutN returns a predicate that is true for nodes whose index is below n.
-func (t *Treap[T]) condCutN(n int) leftCondition[T] {
+func condCutN[T any](n int) leftCondition[T] {
 	return func(nodeValue T, nodeIndex int) bool {
 		return nodeIndex < n
 	}
 }
 
 // InsertLeft inserts value before any equal elements and returns its index.
+// Unless the treap allows duplicates, it behaves as Insert and returns the index of value.
+// Canonical treaps insert after any equal elements instead, as InsertRight, see Options.PriorityHash.
 func (t *Treap[T]) InsertLeft(value T) (index int) {
+	if t.duplicates != DuplicatesAllow {
+		index, _ = t.Insert(value)
+		return index
+	}
+	if t.Canonical() {
+		return t.InsertRight(value)
+	}
+	defer t.debugCheck()
+
 	less, greaterOrEqual := t.root.split(t.condLess(value), 0)
 
 	index = less.safeSize()
 
-	greaterOrEqual = merge(newNode(value, t.randFn()), greaterOrEqual)
+	greaterOrEqual = merge(t.newNode(value, 0), greaterOrEqual)
 	t.root = merge(less, greaterOrEqual)
 
 	return index
 }
 
 // InsertRight inserts value after any equal elements and returns its index.
+// Unless the treap allows duplicates, it behaves as Insert and returns the index of value.
 func (t *Treap[T]) InsertRight(value T) (index int) {
+	if t.duplicates != DuplicatesAllow {
+		index, _ = t.Insert(value)
+		return index
+	}
+	defer t.debugCheck()
+
 	lessOrEqual, greater := t.root.split(t.condLeq(value), 0)
 
 	index = lessOrEqual.safeSize()
 
-	lessOrEqual = merge(lessOrEqual, newNode(value, t.randFn()))
+	rank := 0
+	if t.Canonical() {
+		rank = index - countLeading(lessOrEqual, func(v T) bool { return t.lessFn(v, value) })
+	}
+	lessOrEqual = merge(lessOrEqual, t.newNode(value, rank))
 	t.root = merge(lessOrEqual, greater)
 
 	return index
 }
 
-// EraseAll removes every occurrence of value and reports how many were deleted.
-func (t *Treap[T]) EraseAll(value T) (erasedCount int) {
-	less, greaterOrEqual := t.root.split(t.condLess(value), 0)
+// eraseIndexRange removes the elements with indices in [from, to) and reports how many were erased.
+// Bounds must already be clamped to the treap.
+func (t *Treap[T]) eraseIndexRange(from int, to int) (erasedCount int) {
+	less, greaterOrEqual := t.root.split(condCutN[T](from), 0)
 
-	equal, greater := greaterOrEqual.split(t.condLeq(value), 0)
+	erased, greater := greaterOrEqual.split(condCutN[T](to-from), 0)
+	t.root = t.joinAfterErase(less, erased, greater)
 
-	t.root = merge(less, greater)
+	return erased.safeSize()
+}
 
-	return equal.safeSize()
+// EraseAll removes every occurrence of value and reports how many were deleted.
+func (t *Treap[T]) EraseAll(value T) (erasedCount int) {
+	defer t.debugCheck()
+
+	from, to := t.equalRange(value)
+	return t.eraseIndexRange(from, to)
 }
 
 // EraseLeftmost removes up to n matching values starting from the leftmost occurrence.
 func (t *Treap[T]) EraseLeftmost(value T, n int) (erasedCount int) {
-	less, greaterOrEqual := t.root.split(t.condLess(value), 0)
+	defer t.debugCheck()
 
-	equal, greater := greaterOrEqual.split(t.condLeq(value), 0)
-
-	if n < 0 {
-		n = equal.safeSize()
+	from, to := t.equalRange(value)
+	if n < 0 || n > to-from {
+		n = to - from
 	}
-	equalErased, equalRemainder := equal.split(t.condCutN(n), 0)
-
-	t.root = merge(less, merge(equalRemainder, greater))
-
-	return equalErased.safeSize()
+	return t.eraseIndexRange(from, from+n)
 }
 
 // EraseRightmost removes up to n matching values starting from the rightmost occurrence.
 func (t *Treap[T]) EraseRightmost(value T, n int) (erasedCount int) {
-	less, greaterOrEqual := t.root.split(t.condLess(value), 0)
+	defer t.debugCheck()
 
-	equal, greater := greaterOrEqual.split(t.condLeq(value), 0)
-
-	if n < 0 {
-		n = equal.safeSize()
+	from, to := t.equalRange(value)
+	if n < 0 || n > to-from {
+		n = to - from
 	}
-	remainderN := equal.safeSize() - n
-	equalRemainder, equalErased := equal.split(t.condCutN(remainderN), 0)
-
-	t.root = merge(less, merge(equalRemainder, greater))
-
-	return equalErased.safeSize()
+	return t.eraseIndexRange(to-n, to)
 }
 
 // EraseRange removes values between startValue and endValue.
@@ -153,6 +349,7 @@ func (t *Treap[T]) EraseRange(startValue T, inclusiveStart bool, endValue T, inc
 	if !t.lessFn(startValue, endValue) && (!inclusiveStart || !inclusiveEnd) {
 		panic("when startValue == endValue, both start and end must be inclusive")
 	}
+	defer t.debugCheck()
 
 	var leftRemainder, toErase, rightRemainder *Node[T]
 
@@ -180,6 +377,7 @@ func (t *Treap[T]) EraseAt(index int, count int) (erasedCount int) {
 	if count < 0 {
 		panic("count must not be negative")
 	}
+	defer t.debugCheck()
 
 	sz := t.root.safeSize()
 	if sz == 0 {
@@ -196,13 +394,7 @@ func (t *Treap[T]) EraseAt(index int, count int) (erasedCount int) {
 		return 0
 	}
 
-	leftRemainder, rightRemainder := t.root.split(t.condCutN(index), 0)
-
-	toErase, rightRemainder := rightRemainder.split(t.condCutN(count), 0)
-
-	t.root = merge(leftRemainder, rightRemainder)
-
-	return toErase.safeSize()
+	return t.eraseIndexRange(index, index+min(count, sz-index))
 }
 
 // FindLowerBound returns the first node not less than value along with its index.
@@ -225,7 +417,7 @@ func (t *Treap[T]) At(index int) *Node[T] {
 		index = sz + index
 	}
 
-	node, _ := t.root.lookupLeftmostUnmatch(t.condCutN(index), 0)
+	node, _ := t.root.lookupLeftmostUnmatch(condCutN[T](index), 0)
 	return node
 }
 
@@ -256,47 +448,51 @@ func (t *Treap[T]) Rightmost() *Node[T] {
 
 // PopLeftmost removes and returns the minimum value, reporting success.
 func (t *Treap[T]) PopLeftmost() (value T, ok bool) {
+	defer t.debugCheck()
+
 	if t.root == nil {
 		return value, false
 	}
 
-	var leftmost *Node[T]
-	leftmost, t.root = t.root.split(t.condCutN(1), 0)
+	leftmost, rest := t.root.split(condCutN[T](1), 0)
+	t.root = t.joinAfterErase(nil, leftmost, rest)
 
 	return leftmost.value, true
 }
 
 // PopRightmost removes and returns the maximum value, reporting success.
 func (t *Treap[T]) PopRightmost() (value T, ok bool) {
+	defer t.debugCheck()
+
 	if t.root == nil {
 		return value, false
 	}
 
 	var rightmost *Node[T]
 	cutN := t.root.safeSize() - 1
-	t.root, rightmost = t.root.split(t.condCutN(cutN), 0)
+	t.root, rightmost = t.root.split(condCutN[T](cutN), 0)
 
 	return rightmost.value, true
 }
 
 // split divides the treap into two new treaps based on leftCond and clears the receiver.
+// In a canonical treap a run of equal values cut in two starts over at rank zero on the right.
 func (t *Treap[T]) split(leftCond leftCondition[T]) (left *Treap[T], right *Treap[T]) {
 	less, greaterOrEqual := t.root.split(leftCond, 0)
-
-	left = &Treap[T]{
-		lessFn: t.lessFn,
-		randFn: t.randFn,
-		root:   less,
+	if t.Canonical() && less != nil && greaterOrEqual != nil {
+		if first := greaterOrEqual.Leftmost().value; !t.lessFn(less.Rightmost().value, first) {
+			greaterOrEqual = t.rerank(greaterOrEqual, first)
+		}
 	}
 
-	right = &Treap[T]{
-		lessFn: t.lessFn,
-		randFn: t.randFn,
-		root:   greaterOrEqual,
-	}
+	left = t.withRoot(less)
+	right = t.withRoot(greaterOrEqual)
 
 	t.root = nil
 
+	left.debugCheck()
+	right.debugCheck()
+
 	return left, right
 }
 
@@ -321,7 +517,7 @@ func (t *Treap[T]) Cut(n int) (left *Treap[T], right *Treap[T]) {
 			n = 0 // Everything goes to right
 		}
 	}
-	return t.split(t.condCutN(n))
+	return t.split(condCutN[T](n))
 }
 
 // CountRange returns how many values fall between startValue and endValue.
@@ -364,7 +560,8 @@ func (t *Treap[T]) CountRange(startValue T, inclusiveStart bool, endValue T, inc
 
 // Count reports the number of occurrences of value in the treap.
 func (t *Treap[T]) Count(value T) int {
-	return t.CountRange(value, true, value, true)
+	from, to := t.equalRange(value)
+	return to - from
 }
 
 // Root returns the internal root node of the treap.
@@ -422,6 +619,10 @@ func (t *Treap[T]) ValuesBackwards() iter.Seq[T] {
 // Merge joins two treaps that share the same ordering function.
 // The treaps must use equivalent lessFn comparators, otherwise the
 // resulting treap will have undefined behavior. Both treaps are consumed.
+// Every value of left must not be greater than any value of right;
+// use Union to combine treaps with overlapping ranges. Unless left allows duplicates,
+// the result keeps one element per value, see Options.Duplicates: a right operand that allows
+// duplicates is reduced first in O(n), and a value ending left and starting right is kept once.
 func Merge[T any](left *Treap[T], right *Treap[T]) *Treap[T] {
 	if left == nil {
 		return right
@@ -430,9 +631,20 @@ func Merge[T any](left *Treap[T], right *Treap[T]) *Treap[T] {
 		return left
 	}
 
-	return &Treap[T]{
-		lessFn: left.lessFn,
-		randFn: left.randFn,
-		root:   merge(left.root, right.root),
+	leftRoot, rightRoot := left.root, left.policyRoot(right)
+	if left.duplicates != DuplicatesAllow && leftRoot != nil && rightRoot != nil {
+		last := leftRoot.Rightmost().value
+		if !left.lessFn(last, rightRoot.Leftmost().value) {
+			if left.duplicates == DuplicatesReplace {
+				leftRoot, _ = leftRoot.split(left.condLess(last), 0)
+			} else {
+				_, rightRoot = rightRoot.split(left.condLeq(last), 0)
+			}
+		}
 	}
+
+	merged := left.withRoot(left.joinRuns(leftRoot, rightRoot))
+	merged.debugCheck()
+
+	return merged
 }
@@ -0,0 +1,266 @@
+package gotreap
+
+import (
+	"cmp"
+	"iter"
+	"math/rand/v2"
+)
+
+type mapEntry[K any, V any] struct {
+	key   K
+	value V
+}
+
+// TreapMap is an ordered key/value map backed by a Treap. Keys are unique.
+type TreapMap[K any, V any] struct {
+	lessFn func(a K, b K) bool
+	treap  *Treap[mapEntry[K, V]]
+}
+
+// NewAutoOrderTreapMap builds an empty map using the natural ordering for keys of type K.
+func NewAutoOrderTreapMap[K cmp.Ordered, V any]() *TreapMap[K, V] {
+	return NewTreapMap[K, V](cmp.Less[K])
+}
+
+// NewAutoOrderTreapMapWithRand builds an empty map using the natural ordering for keys of type K and a custom random function.
+func NewAutoOrderTreapMapWithRand[K cmp.Ordered, V any](randFn func() int) *TreapMap[K, V] {
+	return NewTreapMapWithRand[K, V](cmp.Less[K], randFn)
+}
+
+// NewTreapMap constructs an empty map using lessFn for key ordering.
+func NewTreapMap[K any, V any](lessFn func(a K, b K) bool) *TreapMap[K, V] {
+	return NewTreapMapWithRand[K, V](lessFn, rand.Int)
+}
+
+// NewTreapMapWithRand constructs an empty map using lessFn for key ordering and randFn for tree balancing.
+func NewTreapMapWithRand[K any, V any](lessFn func(a K, b K) bool, randFn func() int) *TreapMap[K, V] {
+	if lessFn == nil {
+		panic("lessFn must not be nil")
+	}
+
+	entryLess := func(a mapEntry[K, V], b mapEntry[K, V]) bool {
+		return lessFn(a.key, b.key)
+	}
+
+	return &TreapMap[K, V]{
+		lessFn: lessFn,
+		treap:  NewTreapWithRand(entryLess, randFn),
+	}
+}
+
+// wrap creates a map sharing m's ordering around an already built treap.
+func (m *TreapMap[K, V]) wrap(treap *Treap[mapEntry[K, V]]) *TreapMap[K, V] {
+	return &TreapMap[K, V]{
+		lessFn: m.lessFn,
+		treap:  treap,
+	}
+}
+
+// find returns the node holding key together with its index, or nil and the insertion index.
+func (m *TreapMap[K, V]) find(key K) (node *Node[mapEntry[K, V]], index int) {
+	node, index = m.treap.FindLowerBound(mapEntry[K, V]{key: key})
+	if node == nil {
+		return nil, m.treap.Size()
+	}
+	if m.lessFn(key, node.value.key) {
+		return nil, index
+	}
+	return node, index
+}
+
+// Get returns the value stored under key and reports whether it was present.
+func (m *TreapMap[K, V]) Get(key K) (value V, ok bool) {
+	node, _ := m.find(key)
+	if node == nil {
+		return value, false
+	}
+	return node.value.value, true
+}
+
+// Contains reports whether key is present in the map.
+func (m *TreapMap[K, V]) Contains(key K) bool {
+	node, _ := m.find(key)
+	return node != nil
+}
+
+// Set stores value under key, overwriting any previous value, and reports whether a new key was inserted.
+func (m *TreapMap[K, V]) Set(key K, value V) (inserted bool) {
+	node, _ := m.find(key)
+	if node != nil {
+		node.value.value = value
+		return false
+	}
+	m.treap.InsertLeft(mapEntry[K, V]{key: key, value: value})
+	return true
+}
+
+// Upsert replaces the value stored under key with updateFn(old, found) and returns the stored result.
+// When key is absent, old is the zero value and found is false.
+func (m *TreapMap[K, V]) Upsert(key K, updateFn func(old V, found bool) V) V {
+	node, _ := m.find(key)
+	if node != nil {
+		node.value.value = updateFn(node.value.value, true)
+		return node.value.value
+	}
+
+	var zero V
+	value := updateFn(zero, false)
+	m.treap.InsertLeft(mapEntry[K, V]{key: key, value: value})
+	return value
+}
+
+// GetOrInsert returns the value stored under key, inserting value first if key is absent.
+// The inserted flag reports whether the insertion happened.
+func (m *TreapMap[K, V]) GetOrInsert(key K, value V) (actual V, inserted bool) {
+	node, _ := m.find(key)
+	if node != nil {
+		return node.value.value, false
+	}
+	m.treap.InsertLeft(mapEntry[K, V]{key: key, value: value})
+	return value, true
+}
+
+// Delete removes key from the map and reports whether it was present.
+func (m *TreapMap[K, V]) Delete(key K) (deleted bool) {
+	return m.treap.EraseAll(mapEntry[K, V]{key: key}) > 0
+}
+
+// Rank returns the number of keys strictly less than key, which is the index key has or would have.
+func (m *TreapMap[K, V]) Rank(key K) int {
+	_, index := m.find(key)
+	return index
+}
+
+// At returns the key/value pair located at the provided index.
+// Supports negative indexing where -1 refers to the last pair; ok is false when index is out of range.
+func (m *TreapMap[K, V]) At(index int) (key K, value V, ok bool) {
+	node := m.treap.At(index)
+	if node == nil {
+		return key, value, false
+	}
+	return node.value.key, node.value.value, true
+}
+
+// Leftmost returns the pair with the smallest key, reporting false for an empty map.
+func (m *TreapMap[K, V]) Leftmost() (key K, value V, ok bool) {
+	return m.At(0)
+}
+
+// Rightmost returns the pair with the largest key, reporting false for an empty map.
+func (m *TreapMap[K, V]) Rightmost() (key K, value V, ok bool) {
+	return m.At(-1)
+}
+
+// Size reports the number of keys stored in the map.
+func (m *TreapMap[K, V]) Size() int {
+	return m.treap.Size()
+}
+
+// Empty reports whether the map contains no keys.
+func (m *TreapMap[K, V]) Empty() bool {
+	return m.treap.Empty()
+}
+
+// Clear removes all keys from the map.
+func (m *TreapMap[K, V]) Clear() {
+	m.treap.Clear()
+}
+
+// SplitBefore splits the map into keys less than key and the rest, clearing the receiver.
+func (m *TreapMap[K, V]) SplitBefore(key K) (left *TreapMap[K, V], right *TreapMap[K, V]) {
+	l, r := m.treap.SplitBefore(mapEntry[K, V]{key: key})
+	return m.wrap(l), m.wrap(r)
+}
+
+// SplitAfter splits the map into keys less than or equal to key and the rest, clearing the receiver.
+func (m *TreapMap[K, V]) SplitAfter(key K) (left *TreapMap[K, V], right *TreapMap[K, V]) {
+	l, r := m.treap.SplitAfter(mapEntry[K, V]{key: key})
+	return m.wrap(l), m.wrap(r)
+}
+
+// Cut splits the map into the first n pairs and the remainder, clearing the receiver.
+// Negative n cuts from the end, following Treap.Cut.
+func (m *TreapMap[K, V]) Cut(n int) (left *TreapMap[K, V], right *TreapMap[K, V]) {
+	l, r := m.treap.Cut(n)
+	return m.wrap(l), m.wrap(r)
+}
+
+// All iterates over key/value pairs in ascending key order.
+func (m *TreapMap[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for entry := range m.treap.Values() {
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// Backward iterates over key/value pairs in descending key order.
+func (m *TreapMap[K, V]) Backward() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for entry := range m.treap.ValuesBackwards() {
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys iterates over keys in ascending order.
+func (m *TreapMap[K, V]) Keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		for entry := range m.treap.Values() {
+			if !yield(entry.key) {
+				return
+			}
+		}
+	}
+}
+
+// Values iterates over values in ascending key order.
+func (m *TreapMap[K, V]) Values() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		for entry := range m.treap.Values() {
+			if !yield(entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// Range iterates over pairs with keys between startKey and endKey in ascending order.
+// Each bound is included only when its corresponding inclusive flag is true.
+func (m *TreapMap[K, V]) Range(startKey K, inclusiveStart bool, endKey K, inclusiveEnd bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var cur *Node[mapEntry[K, V]]
+		if inclusiveStart {
+			cur, _ = m.treap.root.lookupLeftmostUnmatch(m.treap.condLess(mapEntry[K, V]{key: startKey}), 0)
+		} else {
+			cur, _ = m.treap.root.lookupLeftmostUnmatch(m.treap.condLeq(mapEntry[K, V]{key: startKey}), 0)
+		}
+
+		for ; cur.Valid(); cur = cur.Next() {
+			key := cur.value.key
+			if m.lessFn(endKey, key) || (!inclusiveEnd && !m.lessFn(key, endKey)) {
+				return
+			}
+			if !yield(key, cur.value.value) {
+				return
+			}
+		}
+	}
+}
+
+// MergeMaps joins two maps that share the same key ordering where every key of left
+// is less than every key of right. Both maps are consumed.
+func MergeMaps[K any, V any](left *TreapMap[K, V], right *TreapMap[K, V]) *TreapMap[K, V] {
+	if left == nil {
+		return right
+	}
+	if right == nil {
+		return left
+	}
+
+	return left.wrap(Merge(left.treap, right.treap))
+}
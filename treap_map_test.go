@@ -0,0 +1,193 @@
+package gotreap
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func requireMapPairs[K any, V any](t *testing.T, m *TreapMap[K, V], expectedKeys []K, expectedValues []V) {
+	t.Helper()
+	keys := []K{}
+	values := []V{}
+	for k, v := range m.All() {
+		keys = append(keys, k)
+		values = append(values, v)
+	}
+	require.Equal(t, expectedKeys, keys)
+	require.Equal(t, expectedValues, values)
+}
+
+func TestTreapMapSetGetDelete(t *testing.T) {
+	m := NewAutoOrderTreapMapWithRand[int, string](staticRand())
+
+	require.True(t, m.Set(5, "five"))
+	require.True(t, m.Set(1, "one"))
+	require.True(t, m.Set(3, "three"))
+	require.False(t, m.Set(3, "THREE"))
+
+	requireMapPairs(t, m, []int{1, 3, 5}, []string{"one", "THREE", "five"})
+
+	v, ok := m.Get(3)
+	require.True(t, ok)
+	require.Equal(t, "THREE", v)
+
+	_, ok = m.Get(4)
+	require.False(t, ok)
+	require.True(t, m.Contains(1))
+	require.False(t, m.Contains(2))
+
+	require.True(t, m.Delete(1))
+	require.False(t, m.Delete(1))
+	requireMapPairs(t, m, []int{3, 5}, []string{"THREE", "five"})
+	require.Equal(t, 2, m.Size())
+}
+
+func TestTreapMapUpsertAndGetOrInsert(t *testing.T) {
+	m := NewAutoOrderTreapMapWithRand[string, int](staticRand())
+
+	for _, word := range []string{"b", "a", "b", "c", "b"} {
+		m.Upsert(word, func(old int, found bool) int { return old + 1 })
+	}
+	requireMapPairs(t, m, []string{"a", "b", "c"}, []int{1, 3, 1})
+
+	actual, inserted := m.GetOrInsert("b", 100)
+	require.False(t, inserted)
+	require.Equal(t, 3, actual)
+
+	actual, inserted = m.GetOrInsert("d", 100)
+	require.True(t, inserted)
+	require.Equal(t, 100, actual)
+}
+
+func TestTreapMapRankAndAt(t *testing.T) {
+	m := NewAutoOrderTreapMapWithRand[int, int](staticRand())
+	for _, k := range []int{10, 20, 30} {
+		m.Set(k, k*k)
+	}
+
+	require.Equal(t, 0, m.Rank(5))
+	require.Equal(t, 1, m.Rank(20))
+	require.Equal(t, 2, m.Rank(25))
+	require.Equal(t, 3, m.Rank(99))
+
+	k, v, ok := m.At(-1)
+	require.True(t, ok)
+	require.Equal(t, 30, k)
+	require.Equal(t, 900, v)
+
+	_, _, ok = m.At(3)
+	require.False(t, ok)
+
+	k, _, _ = m.Leftmost()
+	require.Equal(t, 10, k)
+	k, _, _ = m.Rightmost()
+	require.Equal(t, 30, k)
+}
+
+func TestTreapMapRange(t *testing.T) {
+	m := NewAutoOrderTreapMapWithRand[int, int](staticRand())
+	for k := range 10 {
+		m.Set(k, -k)
+	}
+
+	collect := func(start int, inclStart bool, end int, inclEnd bool) []int {
+		keys := []int{}
+		for k := range m.Range(start, inclStart, end, inclEnd) {
+			keys = append(keys, k)
+		}
+		return keys
+	}
+
+	require.Equal(t, []int{2, 3, 4}, collect(2, true, 4, true))
+	require.Equal(t, []int{3}, collect(2, false, 4, false))
+	require.Empty(t, collect(20, true, 30, true))
+
+	var first []int
+	for k := range m.Range(0, true, 9, true) {
+		first = append(first, k)
+		if len(first) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []int{0, 1}, first)
+
+	var backward []int
+	for k := range m.Backward() {
+		backward = append(backward, k)
+	}
+	require.Equal(t, []int{9, 8, 7, 6, 5, 4, 3, 2, 1, 0}, backward)
+	require.Equal(t, []int{0, -1, -2}, slices.Collect(m.Values())[:3])
+	require.Equal(t, []int{0, 1, 2}, slices.Collect(m.Keys())[:3])
+}
+
+func TestTreapMapSplitCutMerge(t *testing.T) {
+	m := NewAutoOrderTreapMapWithRand[int, string](staticRand())
+	for _, k := range []int{1, 2, 3, 4, 5} {
+		m.Set(k, "v")
+	}
+
+	left, right := m.SplitBefore(3)
+	require.True(t, m.Empty())
+	require.Equal(t, []int{1, 2}, slices.Collect(left.Keys()))
+	require.Equal(t, []int{3, 4, 5}, slices.Collect(right.Keys()))
+
+	merged := MergeMaps(left, right)
+	left, right = merged.SplitAfter(3)
+	require.Equal(t, []int{1, 2, 3}, slices.Collect(left.Keys()))
+	require.Equal(t, []int{4, 5}, slices.Collect(right.Keys()))
+
+	merged = MergeMaps(left, right)
+	left, right = merged.Cut(-1)
+	require.Equal(t, []int{1, 2, 3, 4}, slices.Collect(left.Keys()))
+	require.Equal(t, []int{5}, slices.Collect(right.Keys()))
+
+	left.Set(0, "zero")
+	require.Equal(t, []int{0, 1, 2, 3, 4}, slices.Collect(left.Keys()))
+
+	require.Same(t, left, MergeMaps(left, nil))
+	require.Same(t, right, MergeMaps(nil, right))
+}
+
+func TestTreapMapCustomLessAndClear(t *testing.T) {
+	m := NewTreapMapWithRand[int, int](func(a, b int) bool { return a > b }, staticRand())
+	for k := range 5 {
+		m.Set(k, k)
+	}
+	require.Equal(t, []int{4, 3, 2, 1, 0}, slices.Collect(m.Keys()))
+
+	m.Clear()
+	require.True(t, m.Empty())
+
+	require.Panics(t, func() { NewTreapMap[int, int](nil) })
+}
+
+func TestTreapMapMatchesBuiltinMap(t *testing.T) {
+	m := NewAutoOrderTreapMapWithRand[int, int](staticRand())
+	expected := map[int]int{}
+
+	rnd := rand.New(rand.NewPCG(17, 71))
+	for range 2000 {
+		k := rnd.IntN(200)
+		switch rnd.IntN(3) {
+		case 0:
+			m.Set(k, k*2)
+			expected[k] = k * 2
+		case 1:
+			_, present := expected[k]
+			require.Equal(t, present, m.Delete(k))
+			delete(expected, k)
+		default:
+			v, ok := m.Get(k)
+			ev, eok := expected[k]
+			require.Equal(t, eok, ok)
+			require.Equal(t, ev, v)
+		}
+	}
+
+	require.Equal(t, len(expected), m.Size())
+	require.Equal(t, slices.Sorted(maps.Keys(expected)), slices.Collect(m.Keys()))
+}
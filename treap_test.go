@@ -1,6 +1,7 @@
 package gotreap
 
 import (
+	"cmp"
 	"math/rand/v2"
 	"slices"
 	"testing"
@@ -475,4 +476,69 @@ func TestJumpRight(t *testing.T) {
 	}
 }
 
+func TestCmpTreapMatchesLessTreap(t *testing.T) {
+	byCmp := NewTreapCmpWithRand(cmp.Compare[int], staticRand(), 5, 1, 5, 3)
+	byLess := NewTreapWithRand(cmp.Less[int], staticRand(), 5, 1, 5, 3)
+	requireTreapValues(t, byCmp, 1, 3, 5, 5)
+
+	rnd := rand.New(rand.NewPCG(17, 71))
+	for range 2000 {
+		value := rnd.IntN(40)
+		switch rnd.IntN(5) {
+		case 0:
+			require.Equal(t, byLess.EraseAll(value), byCmp.EraseAll(value))
+		case 1:
+			n := rnd.IntN(4) - 1
+			require.Equal(t, byLess.EraseLeftmost(value, n), byCmp.EraseLeftmost(value, n))
+		case 2:
+			n := rnd.IntN(4) - 1
+			require.Equal(t, byLess.EraseRightmost(value, n), byCmp.EraseRightmost(value, n))
+		default:
+			require.Equal(t, byLess.InsertRight(value), byCmp.InsertRight(value))
+		}
+		require.Equal(t, byLess.Count(value), byCmp.Count(value))
+	}
+
+	require.Equal(t, mustValues(byLess), mustValues(byCmp))
+	require.NoError(t, byCmp.Validate())
+
+	left, right := byCmp.SplitBefore(20)
+	merged := Merge(left, right)
+	merged.InsertLeft(7)
+	require.Equal(t, byLess.Count(7)+1, merged.Count(7))
+}
+
+func TestCmpTreapHalvesEqualityComparisons(t *testing.T) {
+	values := make([]int, 4096)
+	for i := range values {
+		values[i] = i / 2
+	}
+
+	calls := 0
+	counted := func(a, b int) int {
+		calls++
+		return cmp.Compare(a, b)
+	}
+	lessCalls := 0
+	countedLess := func(a, b int) bool {
+		lessCalls++
+		return a < b
+	}
+
+	byCmp := NewTreapCmpWithRand(counted, staticRand(), values...)
+	byLess := NewTreapWithRand(countedLess, staticRand(), values...)
+	calls, lessCalls = 0, 0
+
+	for v := range 2048 {
+		require.Equal(t, 2, byCmp.Count(v))
+		require.Equal(t, 2, byLess.Count(v))
+	}
+	require.Less(t, calls*10, lessCalls*7)
+}
+
+func TestCmpConstructorsPanicOnNil(t *testing.T) {
+	require.Panics(t, func() { NewTreapCmp[int](nil) })
+	require.Panics(t, func() { NewTreapCmpWithRand(cmp.Compare[int], nil) })
+}
+
 // TODO: fuzzing
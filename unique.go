@@ -0,0 +1,198 @@
+package gotreap
+
+// DuplicatePolicy selects what inserting a value equal to a stored one does.
+type DuplicatePolicy int
+
+const (
+	// DuplicatesAllow stores every inserted value, keeping equal values side by side.
+	DuplicatesAllow DuplicatePolicy = iota
+	// DuplicatesReject keeps the stored value and drops the inserted one.
+	DuplicatesReject
+	// DuplicatesReplace overwrites the stored value with the inserted one in place.
+	DuplicatesReplace
+)
+
+// uniqueBuilder feeds values arriving in ascending order to a sortedBuilder, applying the
+// treap's duplicate policy to runs of equal values: DuplicatesReject keeps the first value
+// of a run and DuplicatesReplace the last. It holds one value back until the next one shows
+// whether it starts a new run. When duplicates are allowed it tracks the rank of every value
+// within its run instead, which canonical treaps derive priorities from.
+type uniqueBuilder[T any] struct {
+	t          *Treap[T]
+	builder    sortedBuilder[T]
+	pending    T
+	hasPending bool
+	last       T
+	hasLast    bool
+	rank       int
+}
+
+// newUniqueBuilder returns a builder for nodes of t.
+func (t *Treap[T]) newUniqueBuilder() *uniqueBuilder[T] {
+	return &uniqueBuilder[T]{t: t}
+}
+
+// append adds value as the new rightmost element unless the duplicate policy drops it.
+func (b *uniqueBuilder[T]) append(value T) {
+	if b.t.duplicates == DuplicatesAllow {
+		b.builder.append(b.t.newNode(value, b.rankOf(value)))
+		return
+	}
+
+	if b.hasPending && !b.t.lessFn(b.pending, value) {
+		if b.t.duplicates == DuplicatesReplace {
+			b.pending = value
+		}
+		return
+	}
+	if b.hasPending {
+		b.builder.append(b.t.newNode(b.pending, 0))
+	}
+	b.pending, b.hasPending = value, true
+}
+
+// rankOf returns the rank of value, the next value in order, within its run of equal values.
+// Only canonical treaps use ranks, so it is zero otherwise.
+func (b *uniqueBuilder[T]) rankOf(value T) int {
+	if !b.t.Canonical() {
+		return 0
+	}
+	if b.hasLast && !b.t.lessFn(b.last, value) {
+		b.rank++
+	} else {
+		b.rank = 0
+	}
+	b.last, b.hasLast = value, true
+	return b.rank
+}
+
+// finish appends the held back value and returns the root of the built tree.
+func (b *uniqueBuilder[T]) finish() *Node[T] {
+	if b.hasPending {
+		b.builder.append(b.t.newNode(b.pending, 0))
+		b.hasPending = false
+	}
+	return b.builder.finish()
+}
+
+// policyRoot returns the tree of other, which is being consumed, for combining into t.
+// When t does not allow duplicates but other does, runs of equal values in other are first
+// reduced to the element t's policy keeps, as uniqueBuilder does; this costs O(n) for n elements
+// of other and reuses the surviving nodes.
+func (t *Treap[T]) policyRoot(other *Treap[T]) *Node[T] {
+	root := other.root
+	if t.duplicates == DuplicatesAllow || other.duplicates != DuplicatesAllow || root == nil {
+		return root
+	}
+
+	nodes := make([]*Node[T], 0, root.safeSize())
+	for cur := root.Leftmost(); cur != nil; cur = cur.Next() {
+		nodes = append(nodes, cur)
+	}
+
+	var builder sortedBuilder[T]
+	for i, node := range nodes {
+		if t.duplicates == DuplicatesReject && i > 0 && !t.lessFn(nodes[i-1].value, node.value) {
+			continue
+		}
+		if t.duplicates == DuplicatesReplace && i+1 < len(nodes) && !t.lessFn(node.value, nodes[i+1].value) {
+			continue
+		}
+		builder.append(t.isolate(node))
+	}
+	return builder.finish()
+}
+
+// DuplicatePolicy reports the duplicate policy the treap was constructed with.
+func (t *Treap[T]) DuplicatePolicy() DuplicatePolicy {
+	return t.duplicates
+}
+
+// findLeftmostEqual returns the leftmost element equal to value, or nil, along with its index,
+// or the index value would be inserted at when there is none, in a single descent.
+func (t *Treap[T]) findLeftmostEqual(value T) (node *Node[T], index int) {
+	offset := 0
+	for cur := t.root; cur != nil; {
+		var c int
+		switch {
+		case t.cmpFn != nil:
+			c = t.cmpFn(cur.value, value)
+		case t.lessFn(cur.value, value):
+			c = -1
+		case t.lessFn(value, cur.value):
+			c = 1
+		}
+
+		if c < 0 {
+			offset += cur.left.safeSize() + 1
+			cur = cur.right
+			continue
+		}
+		if c == 0 {
+			node, index = cur, offset+cur.left.safeSize()
+		}
+		cur = cur.left
+	}
+
+	if node == nil {
+		index = offset
+	}
+	return node, index
+}
+
+// insertNew links a new node holding value at index, which must keep the order and have no
+// equal neighbours, and returns it.
+func (t *Treap[T]) insertNew(value T, index int) *Node[T] {
+	node := t.newNode(value, 0)
+	t.insertNodeAt(node, index)
+	return node
+}
+
+// Insert adds value according to the treap's duplicate policy and returns its index.
+// inserted reports whether the treap grew: with DuplicatesAllow value is always added after any
+// equal elements, with DuplicatesReject an equal element is kept as is, and with DuplicatesReplace
+// it is overwritten by value. An equal element is looked up and the insertion point found in
+// the same descent.
+func (t *Treap[T]) Insert(value T) (index int, inserted bool) {
+	if t.duplicates == DuplicatesAllow {
+		return t.InsertRight(value), true
+	}
+
+	node, index := t.findLeftmostEqual(value)
+	if node == nil {
+		defer t.debugCheck()
+		t.insertNew(value, index)
+		return index, true
+	}
+	if t.duplicates == DuplicatesReplace {
+		index = t.ReplaceValue(node, value)
+	}
+	return index, false
+}
+
+// ReplaceOrInsert overwrites the leftmost element equal to value with value and returns the
+// previous one, or inserts value when there is no equal element, whatever the duplicate policy.
+func (t *Treap[T]) ReplaceOrInsert(value T) (old T, replaced bool) {
+	node, index := t.findLeftmostEqual(value)
+	if node == nil {
+		defer t.debugCheck()
+		t.insertNew(value, index)
+		return old, false
+	}
+
+	old = node.value
+	t.ReplaceValue(node, value)
+	return old, true
+}
+
+// GetOrInsert returns the leftmost element equal to value, or inserts value and returns its new
+// node when there is none, whatever the duplicate policy. inserted reports which happened.
+func (t *Treap[T]) GetOrInsert(value T) (node *Node[T], inserted bool) {
+	node, index := t.findLeftmostEqual(value)
+	if node != nil {
+		return node, false
+	}
+
+	defer t.debugCheck()
+	return t.insertNew(value, index), true
+}
@@ -0,0 +1,257 @@
+package gotreap
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type labeled struct {
+	Key   int
+	Label string
+}
+
+func byLabeledKey(a, b labeled) int { return cmp.Compare(a.Key, b.Key) }
+
+func newLabeledSet(policy DuplicatePolicy, values ...labeled) *Treap[labeled] {
+	return NewTreapCmpWithOptions(byLabeledKey, Options[labeled]{Duplicates: policy, RandFn: staticRand()}, values...)
+}
+
+func TestInsertPolicies(t *testing.T) {
+	allow := newLabeledSet(DuplicatesAllow, labeled{1, "a"})
+	index, inserted := allow.Insert(labeled{1, "b"})
+	require.Equal(t, 1, index)
+	require.True(t, inserted)
+	require.Equal(t, 2, allow.Size())
+
+	reject := newLabeledSet(DuplicatesReject, labeled{1, "a"}, labeled{3, "c"})
+	index, inserted = reject.Insert(labeled{3, "z"})
+	require.Equal(t, 1, index)
+	require.False(t, inserted)
+	index, inserted = reject.Insert(labeled{2, "b"})
+	require.Equal(t, 1, index)
+	require.True(t, inserted)
+	require.Equal(t, []labeled{{1, "a"}, {2, "b"}, {3, "c"}}, mustValues(reject))
+
+	replace := newLabeledSet(DuplicatesReplace, labeled{1, "a"}, labeled{3, "c"})
+	index, inserted = replace.Insert(labeled{3, "z"})
+	require.Equal(t, 1, index)
+	require.False(t, inserted)
+	require.Equal(t, []labeled{{1, "a"}, {3, "z"}}, mustValues(replace))
+	require.NoError(t, replace.Validate())
+}
+
+func TestInsertLeftRightFollowPolicy(t *testing.T) {
+	reject := newLabeledSet(DuplicatesReject, labeled{1, "a"})
+	require.Equal(t, 0, reject.InsertLeft(labeled{1, "b"}))
+	require.Equal(t, 0, reject.InsertRight(labeled{1, "c"}))
+	require.Equal(t, 1, reject.InsertRight(labeled{2, "d"}))
+	require.Equal(t, []labeled{{1, "a"}, {2, "d"}}, mustValues(reject))
+
+	replace := newLabeledSet(DuplicatesReplace, labeled{1, "a"})
+	replace.InsertRight(labeled{1, "b"})
+	require.Equal(t, []labeled{{1, "b"}}, mustValues(replace))
+	require.Equal(t, DuplicatesReplace, replace.DuplicatePolicy())
+}
+
+func TestReplaceOrInsertAndGetOrInsert(t *testing.T) {
+	tr := newLabeledSet(DuplicatesAllow, labeled{1, "a"}, labeled{1, "b"}, labeled{2, "c"})
+
+	old, replaced := tr.ReplaceOrInsert(labeled{1, "x"})
+	require.True(t, replaced)
+	require.Equal(t, labeled{1, "a"}, old)
+	old, replaced = tr.ReplaceOrInsert(labeled{0, "y"})
+	require.False(t, replaced)
+	require.Zero(t, old)
+	require.Equal(t, []labeled{{0, "y"}, {1, "x"}, {1, "b"}, {2, "c"}}, mustValues(tr))
+
+	node, inserted := tr.GetOrInsert(labeled{1, "ignored"})
+	require.False(t, inserted)
+	require.Equal(t, labeled{1, "x"}, node.Value())
+	require.Equal(t, 1, node.Index())
+
+	node, inserted = tr.GetOrInsert(labeled{5, "new"})
+	require.True(t, inserted)
+	require.Equal(t, 4, node.Index())
+	require.Equal(t, 5, tr.Size())
+	require.NoError(t, tr.Validate())
+
+	canonical := NewTreapCmpWithOptions(byLabeledKey, Options[labeled]{
+		Duplicates:   DuplicatesReplace,
+		PriorityHash: func(v labeled) uint64 { return uint64(v.Key)*7 + uint64(len(v.Label)) },
+	}, labeled{1, "a"}, labeled{2, "b"})
+	old, replaced = canonical.ReplaceOrInsert(labeled{2, "longer"})
+	require.True(t, replaced)
+	require.Equal(t, labeled{2, "b"}, old)
+	require.NoError(t, canonical.Validate())
+}
+
+func TestUniqueConstructionAndDecoding(t *testing.T) {
+	input := []labeled{{2, "first"}, {1, "x"}, {2, "second"}, {2, "third"}}
+	require.Equal(t, []labeled{{1, "x"}, {2, "first"}}, mustValues(newLabeledSet(DuplicatesReject, slices.Clone(input)...)))
+	require.Equal(t, []labeled{{1, "x"}, {2, "third"}}, mustValues(newLabeledSet(DuplicatesReplace, slices.Clone(input)...)))
+
+	lessFn := func(a, b labeled) bool { return a.Key < b.Key }
+	sorted := []labeled{{1, "x"}, {2, "first"}, {2, "second"}, {3, "y"}}
+	fromSorted, err := FromSortedSlice(lessFn, sorted, Options[labeled]{Duplicates: DuplicatesReplace}, true)
+	require.NoError(t, err)
+	require.Equal(t, []labeled{{1, "x"}, {2, "second"}, {3, "y"}}, mustValues(fromSorted))
+
+	ints := NewTreapWithOptions(cmp.Less[int], Options[int]{Duplicates: DuplicatesReject})
+	require.NoError(t, json.Unmarshal([]byte("[3, 1, 3, 2, 1]"), ints))
+	requireTreapValues(t, ints, 1, 2, 3)
+
+	var buf bytes.Buffer
+	require.NoError(t, NewEncoder(&buf, IntCodec[int]{}).Encode(NewAutoOrderTreap(1, 1, 2, 2, 2)))
+	decoded := NewTreapWithOptions(cmp.Less[int], Options[int]{Duplicates: DuplicatesReject})
+	require.NoError(t, NewDecoder(&buf, IntCodec[int]{}).Decode(decoded))
+	requireTreapValues(t, decoded, 1, 2)
+	require.NoError(t, decoded.Validate())
+}
+
+func TestUniqueMatchesCountThenInsert(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(15, 16))
+	set := NewTreapWithOptions(cmp.Less[int], Options[int]{Duplicates: DuplicatesReject})
+	reference := NewAutoOrderTreap[int]()
+	for range 2000 {
+		v := rnd.IntN(300)
+		index, inserted := set.Insert(v)
+		require.Equal(t, reference.Count(v) == 0, inserted)
+		if inserted {
+			reference.InsertRight(v)
+		}
+		_, expectedIndex := reference.FindLowerBound(v)
+		require.Equal(t, expectedIndex, index)
+		if rnd.IntN(4) == 0 {
+			w := rnd.IntN(300)
+			require.Equal(t, reference.EraseAll(w), set.EraseAll(w))
+		}
+	}
+	require.Equal(t, mustValues(reference), mustValues(set))
+	require.NoError(t, set.Validate())
+}
+
+func TestValidateReportsDuplicatesInUniqueTreap(t *testing.T) {
+	tr := NewTreapWithOptions(cmp.Less[int], Options[int]{Duplicates: DuplicatesReject}, 1, 2, 3)
+	tr.At(2).value = 2
+	require.ErrorIs(t, tr.Validate(), ErrInvariantViolated)
+}
+
+func TestSyncTreapUniqueInserts(t *testing.T) {
+	s := NewSyncTreap(NewTreapWithOptions(cmp.Less[int], Options[int]{Duplicates: DuplicatesReject}, 1, 3))
+	index, inserted := s.Insert(3)
+	require.Equal(t, 1, index)
+	require.False(t, inserted)
+
+	stored, inserted := s.GetOrInsert(2)
+	require.True(t, inserted)
+	require.Equal(t, 2, stored)
+
+	old, replaced := s.ReplaceOrInsert(1)
+	require.True(t, replaced)
+	require.Equal(t, 1, old)
+	require.Equal(t, []int{1, 2, 3}, s.Snapshot())
+}
+
+func TestSetOperationsFollowPolicy(t *testing.T) {
+	left := []labeled{{1, "l"}, {2, "l"}, {3, "l"}}
+	right := []labeled{{2, "r"}, {3, "r"}, {4, "r"}}
+
+	union := Union(newLabeledSet(DuplicatesReject, left...), newLabeledSet(DuplicatesReject, right...), MultiplicitySum)
+	require.Equal(t, []labeled{{1, "l"}, {2, "l"}, {3, "l"}, {4, "r"}}, mustValues(union))
+	require.NoError(t, union.Validate())
+
+	union = Union(newLabeledSet(DuplicatesReplace, left...), newLabeledSet(DuplicatesReplace, right...), MultiplicityMax)
+	require.Equal(t, []labeled{{1, "l"}, {2, "r"}, {3, "r"}, {4, "r"}}, mustValues(union))
+
+	intersection := Intersection(newLabeledSet(DuplicatesReplace, left...), newLabeledSet(DuplicatesReplace, right...), MultiplicityMin)
+	require.Equal(t, []labeled{{2, "r"}, {3, "r"}}, mustValues(intersection))
+
+	// A right operand holding duplicates is collapsed too.
+	union = Union(newLabeledSet(DuplicatesReject, left...), newLabeledSet(DuplicatesAllow, labeled{5, "a"}, labeled{5, "b"}), MultiplicitySum)
+	require.Equal(t, []labeled{{1, "l"}, {2, "l"}, {3, "l"}, {5, "a"}}, mustValues(union))
+	require.NoError(t, union.Validate())
+
+	canonical := Options[int]{Duplicates: DuplicatesReject, PriorityHash: CodecHash[int](IntCodec[int]{}, 1)}
+	ints := Union(NewTreapWithOptions(cmp.Less[int], canonical, 1, 2, 3), NewTreapWithOptions(cmp.Less[int], canonical, 2, 3, 4), MultiplicitySum)
+	require.NoError(t, ints.Validate())
+	requireTreapValues(t, ints, 1, 2, 3, 4)
+
+	merged := Merge(newLabeledSet(DuplicatesReject, labeled{1, "l"}), newLabeledSet(DuplicatesReject, labeled{1, "r"}, labeled{2, "r"}))
+	require.Equal(t, []labeled{{1, "l"}, {2, "r"}}, mustValues(merged))
+	merged = Merge(newLabeledSet(DuplicatesReplace, labeled{0, "l"}, labeled{1, "l"}), newLabeledSet(DuplicatesReplace, labeled{1, "r"}))
+	require.Equal(t, []labeled{{0, "l"}, {1, "r"}}, mustValues(merged))
+	require.NoError(t, merged.Validate())
+}
+
+func TestNodeEditsRejectEqualValuesInSets(t *testing.T) {
+	set := newLabeledSet(DuplicatesReject, labeled{1, "a"}, labeled{3, "c"})
+	first := set.At(0)
+	require.Panics(t, func() { set.InsertAfterNode(first, labeled{1, "b"}) })
+	require.Panics(t, func() { set.InsertBeforeNode(set.At(1), labeled{3, "b"}) })
+	require.Panics(t, func() { set.ReplaceValue(first, labeled{3, "z"}) })
+
+	set.InsertAfterNode(first, labeled{2, "b"})
+	require.Equal(t, 0, set.ReplaceValue(first, labeled{1, "x"}))
+	require.Equal(t, 2, set.ReplaceValue(first, labeled{4, "x"}))
+	require.Equal(t, []labeled{{2, "b"}, {3, "c"}, {4, "x"}}, mustValues(set))
+	require.NoError(t, set.Validate())
+}
+
+func TestSetOperationsReduceOperandsAllowingDuplicates(t *testing.T) {
+	rnd := rand.New(rand.NewPCG(19, 20))
+	random := func(policy DuplicatePolicy, lo, hi int) *Treap[int] {
+		values := make([]int, rnd.IntN(20))
+		for i := range values {
+			values[i] = lo + rnd.IntN(hi-lo)
+		}
+		return NewTreapWithOptions(cmp.Less[int], Options[int]{Duplicates: policy, RandFn: rnd.Int}, values...)
+	}
+	unique := func(values []int) []int {
+		return slices.Compact(slices.Sorted(slices.Values(values)))
+	}
+
+	for range 500 {
+		policy := DuplicatesReject + DuplicatePolicy(rnd.IntN(2))
+		a, b := random(policy, 0, 10), random(DuplicatesAllow, 0, 10)
+		va, vb := mustValues(a), mustValues(b)
+
+		var result *Treap[int]
+		var expected []int
+		switch rnd.IntN(4) {
+		case 0:
+			result, expected = Union(a, b, Multiplicity(rnd.IntN(3))), unique(append(va, vb...))
+		case 1:
+			result = SymmetricDifference(a, b)
+			for _, v := range unique(append(va, vb...)) {
+				if slices.Contains(va, v) != slices.Contains(vb, v) {
+					expected = append(expected, v)
+				}
+			}
+		case 2:
+			result = Intersection(a, b, Multiplicity(rnd.IntN(3)))
+			for _, v := range va {
+				if slices.Contains(vb, v) {
+					expected = append(expected, v)
+				}
+			}
+		default:
+			b = random(DuplicatesAllow, 9, 20)
+			vb = mustValues(b)
+			result, expected = Merge(a, b), unique(append(va, vb...))
+		}
+		require.NoError(t, result.Validate())
+		require.Equal(t, len(expected), result.Size())
+		if len(expected) > 0 {
+			require.Equal(t, expected, mustValues(result))
+		}
+	}
+
+	merged := Merge(NewTreapWithOptions(cmp.Less[int], Options[int]{Duplicates: DuplicatesReject}, 1, 2, 3), NewAutoOrderTreap(5, 5, 6))
+	requireTreapValues(t, merged, 1, 2, 3, 5, 6)
+}
@@ -0,0 +1,113 @@
+package gotreap
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvariantViolated is returned by Validate when the tree structure is inconsistent.
+var ErrInvariantViolated = errors.New("gotreap: invariant violated")
+
+// Validate checks the structural invariants of the treap: values are in order under lessFn,
+// no node has a higher heightPriority than its parent, cached subtree sizes are correct
+// and parent pointers match the tree shape. The returned error wraps ErrInvariantViolated
+// and names the in-order index of the first offending node. Canonical treaps are also checked
+// to use hashed priorities ranked within runs of equal values, with ties between equal
+// priorities always resolved the same way,
+// and treaps that do not allow duplicates to hold no equal values.
+// Runs in O(n); intended for tests and debugging.
+func (t *Treap[T]) Validate() error {
+	if t.root == nil {
+		return nil
+	}
+	if t.root.parent != nil {
+		return fmt.Errorf("%w: root has a parent", ErrInvariantViolated)
+	}
+	v := validator[T]{lessFn: t.lessFn, priorityHash: t.priorityHash, unique: t.duplicates != DuplicatesAllow}
+	_, err := v.validate(t.root, 0)
+	return err
+}
+
+// debugCheck panics if the treap is broken. Compiled to a no-op unless built with the gotreap_debug tag.
+func (t *Treap[T]) debugCheck() {
+	if !debugMode {
+		return
+	}
+	if err := t.Validate(); err != nil {
+		panic(err)
+	}
+}
+
+// validator walks a tree in order, remembering the previous value to check ordering.
+type validator[T any] struct {
+	lessFn       func(a T, b T) bool
+	priorityHash func(value T) uint64
+	unique       bool
+	prev         T
+	hasPrev      bool
+	// rank is the position of the previous value within its run of equal values.
+	rank int
+}
+
+// validate checks the subtree rooted at node, whose leftmost element has index offset,
+// and returns its actual size.
+func (v *validator[T]) validate(node *Node[T], offset int) (size int, err error) {
+	for _, child := range [2]*Node[T]{node.left, node.right} {
+		if child == nil {
+			continue
+		}
+		// Checking parent links before descending also stops the walk on cycles.
+		if child.parent != node {
+			return 0, v.errorf(offset+node.left.safeSize(), "child has a mismatched parent pointer")
+		}
+		if child.heightPriority > node.heightPriority {
+			return 0, v.errorf(offset+node.left.safeSize(), "child has a higher heightPriority than the node")
+		}
+	}
+	// Canonical shapes keep the leftmost of equal priorities on top, as merge does. Ranks make
+	// equal values differ in priority, so ties only come from colliding hashes.
+	if v.priorityHash != nil && node.left != nil && node.left.heightPriority == node.heightPriority {
+		return 0, v.errorf(offset+node.left.safeSize(), "left child has the same heightPriority as the node")
+	}
+
+	leftSize := 0
+	if node.left != nil {
+		if leftSize, err = v.validate(node.left, offset); err != nil {
+			return 0, err
+		}
+	}
+
+	index := offset + leftSize
+	if v.hasPrev && v.lessFn != nil && v.lessFn(node.value, v.prev) {
+		return 0, v.errorf(index, "value is less than its predecessor")
+	}
+	if v.hasPrev && v.unique && v.lessFn != nil && !v.lessFn(v.prev, node.value) {
+		return 0, v.errorf(index, "value equals its predecessor in a treap without duplicates")
+	}
+	if v.hasPrev && v.lessFn != nil && !v.lessFn(v.prev, node.value) {
+		v.rank++
+	} else {
+		v.rank = 0
+	}
+	if v.priorityHash != nil && node.heightPriority != rankedPriority(v.priorityHash(node.value), v.rank) {
+		return 0, v.errorf(index, "heightPriority does not match the priority hash at rank %d", v.rank)
+	}
+	v.prev, v.hasPrev = node.value, true
+
+	rightSize := 0
+	if node.right != nil {
+		if rightSize, err = v.validate(node.right, index+1); err != nil {
+			return 0, err
+		}
+	}
+
+	size = leftSize + 1 + rightSize
+	if node.size != size {
+		return 0, v.errorf(index, "cached size %d, actual %d", node.size, size)
+	}
+	return size, nil
+}
+
+func (v *validator[T]) errorf(index int, format string, args ...any) error {
+	return fmt.Errorf("%w: node at index %d: %s", ErrInvariantViolated, index, fmt.Sprintf(format, args...))
+}
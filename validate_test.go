@@ -0,0 +1,80 @@
+package gotreap
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAcceptsHealthyTrees(t *testing.T) {
+	require.NoError(t, NewAutoOrderTreap[int]().Validate())
+
+	var zero Treap[int]
+	require.NoError(t, zero.Validate())
+
+	tr := NewAutoOrderTreapWithRand(staticRand(), 5, 3, 3, 9, 1)
+	require.NoError(t, tr.Validate())
+
+	rnd := rand.New(rand.NewPCG(3, 4))
+	for range 500 {
+		tr.InsertRight(rnd.IntN(100))
+		if rnd.IntN(3) == 0 {
+			tr.EraseAt(rnd.IntN(tr.Size()), 2)
+		}
+	}
+	require.NoError(t, tr.Validate())
+
+	left, right := tr.SplitBefore(50)
+	require.NoError(t, left.Validate())
+	require.NoError(t, right.Validate())
+}
+
+func TestValidateReportsViolations(t *testing.T) {
+	corrupt := func(fn func(tr *Treap[int])) error {
+		tr := NewAutoOrderTreapWithRand(staticRand(), 0, 1, 2, 3, 4, 5, 6, 7)
+		fn(tr)
+		return tr.Validate()
+	}
+
+	err := corrupt(func(tr *Treap[int]) { tr.At(3).value = 100 })
+	require.ErrorIs(t, err, ErrInvariantViolated)
+	require.ErrorContains(t, err, "less than its predecessor")
+
+	err = corrupt(func(tr *Treap[int]) { tr.At(5).size++ })
+	require.ErrorIs(t, err, ErrInvariantViolated)
+	require.ErrorContains(t, err, "node at index 5: cached size")
+
+	err = corrupt(func(tr *Treap[int]) {
+		child := tr.root.left
+		if child == nil {
+			child = tr.root.right
+		}
+		child.heightPriority = tr.root.heightPriority + 1
+	})
+	require.ErrorContains(t, err, "higher heightPriority")
+
+	err = corrupt(func(tr *Treap[int]) {
+		child := tr.root.left
+		if child == nil {
+			child = tr.root.right
+		}
+		child.parent = nil
+	})
+	require.ErrorContains(t, err, "mismatched parent pointer")
+
+	err = corrupt(func(tr *Treap[int]) { tr.root.parent = tr.root.left })
+	require.ErrorContains(t, err, "root has a parent")
+}
+
+func TestValidateCatchesInconsistentMerge(t *testing.T) {
+	// Merging overlapping treaps is a misuse that Validate must detect.
+	merged := func() *Treap[int] {
+		return Merge(NewAutoOrderTreapWithRand(staticRand(), 5, 6), NewAutoOrderTreapWithRand(staticRand(), 1, 2))
+	}
+	if debugMode {
+		require.Panics(t, func() { merged() })
+		return
+	}
+	require.ErrorIs(t, merged().Validate(), ErrInvariantViolated)
+}